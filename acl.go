@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mediocregopher/radix/v3"
+	"log"
+	"strings"
+)
+
+// aclPreflightCmd is a side effect free form of a benchmark command, used to check
+// the ACL permissions of the user before the benchmark starts.
+type aclPreflightCmd struct {
+	name   string
+	action radix.Action
+	// the part of the report missing when the command is not allowed, for the commands the benchmark
+	// can run without. Empty for the required commands
+	missing string
+}
+
+// getACLPreflightCmds returns the commands issued by the selected -mode and -query on the given key.
+// The load probe is a ZADD XX INCR by 0 of a member that is not expected to exist, which never
+// modifies the key, while the query probes are the query commands themselves.
+func getACLPreflightCmds(isLoad bool, query string, multi bool, key string) []aclPreflightCmd {
+	cmds := []aclPreflightCmd{}
+	if isLoad {
+		cmds = append(cmds, aclPreflightCmd{"ZADD", radix.Cmd(nil, "ZADD", key, "XX", "INCR", "0", "redis-zbench-go:acl-preflight"), ""})
+		return cmds
+	}
+	switch query {
+	case "zrange-byscore-rev":
+		cmds = append(cmds, aclPreflightCmd{"ZREVRANGEBYSCORE", radix.Cmd(nil, "ZREVRANGEBYSCORE", key, "1", "0"), ""})
+	case "zrange-byscore":
+		cmds = append(cmds, aclPreflightCmd{"ZRANGE", radix.Cmd(nil, "ZRANGE", key, "0", "1", "BYSCORE"), ""})
+	case "zrange-withscores":
+		cmds = append(cmds, aclPreflightCmd{"ZRANGE", radix.Cmd(nil, "ZRANGE", key, "0", "1", "BYSCORE", "WITHSCORES"), ""})
+	case "zrevrangebylex":
+		cmds = append(cmds, aclPreflightCmd{"ZREVRANGEBYLEX", radix.Cmd(nil, "ZREVRANGEBYLEX", key, "[a", "-"), ""})
+	case "zscore":
+		cmds = append(cmds, aclPreflightCmd{"ZRANDMEMBER", radix.Cmd(nil, "ZRANDMEMBER", key), ""})
+		cmds = append(cmds, aclPreflightCmd{"ZSCORE", radix.Cmd(nil, "ZSCORE", key, "redis-zbench-go:acl-preflight"), ""})
+	}
+	if multi {
+		// MULTI and EXEC are issued on the same connection, as an empty transaction
+		cmds = append(cmds, aclPreflightCmd{"MULTI/EXEC", radix.Pipeline(radix.Cmd(nil, "MULTI"), radix.Cmd(nil, "EXEC")), ""})
+	}
+	return cmds
+}
+
+// getACLReportCmds returns the commands issued besides the benchmark ones, for the options that enable them.
+// Apart from CONFIG GET on -encoding-aware, which needs the encoding thresholds, the benchmark runs without them.
+func getACLReportCmds(isLoad bool, encodingAware bool, encodingSamples bool, memorySamples bool, key string) []aclPreflightCmd {
+	configMissing := "server configs on the preflight checks"
+	if encodingAware {
+		configMissing = ""
+	}
+	cmds := []aclPreflightCmd{
+		{"INFO", radix.Cmd(nil, "INFO", "server"), "server preflight checks and server side stats"},
+		{"CONFIG GET", radix.Cmd(nil, "CONFIG", "GET", "maxmemory"), configMissing},
+		{"CLIENT SETNAME", radix.Cmd(nil, "CLIENT", "SETNAME", benchClientName), "name of the benchmark connections"},
+		{"CLIENT LIST", radix.Cmd(nil, "CLIENT", "LIST"), "server side connections summary"},
+	}
+	if isLoad && encodingAware && encodingSamples {
+		cmds = append(cmds, aclPreflightCmd{"OBJECT ENCODING", radix.Cmd(nil, "OBJECT", "ENCODING", key), "encodings verification after the load"})
+	}
+	if isLoad && memorySamples {
+		cmds = append(cmds, aclPreflightCmd{"MEMORY USAGE", radix.Cmd(nil, "MEMORY", "USAGE", key), "MEMORY USAGE figures of the memory footprint"})
+		cmds = append(cmds, aclPreflightCmd{"ZCARD", radix.Cmd(nil, "ZCARD", key), "MEMORY USAGE figures of the memory footprint"})
+	}
+	return cmds
+}
+
+// checkACLPermissions issues the preflight commands on the node serving the key, and returns
+// an error naming the first required command rejected with NOPERM, along with a warning for each of
+// the other rejected commands. Other errors are only logged, given that they do not prevent the benchmark from running.
+func checkACLPermissions(conn radix.Client, user string, key string, cmds []aclPreflightCmd) ([]string, error) {
+	warnings := []string{}
+	client, err := getClientForKey(conn, key)
+	if err != nil {
+		return warnings, err
+	}
+	var whoami string
+	if err := client.Do(radix.Cmd(&whoami, "ACL", "WHOAMI")); err == nil {
+		user = whoami
+	}
+	for _, cmd := range cmds {
+		err := client.Do(cmd.action)
+		if err == nil {
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), "NOPERM") {
+			log.Printf("Received an error while checking the permissions to run %s: %v", cmd.name, err)
+			continue
+		}
+		if cmd.missing == "" {
+			return warnings, fmt.Errorf("user %s is not allowed to run %s on key %s: %v", user, cmd.name, key, err)
+		}
+		warnings = append(warnings, fmt.Sprintf("user %s is not allowed to run %s. The %s will be missing", user, cmd.name, cmd.missing))
+	}
+	return warnings, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/mediocregopher/radix/v3"
+	"golang.org/x/time/rate"
+	"log"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// churnConfig holds the connection settings of -mode=churn, where every client repeatedly
+// connects, issues AUTH and SELECT on its own, runs a few commands and disconnects.
+type churnConfig struct {
+	network string
+	addr    string
+	// dial options, excluding AUTH
+	opts            []radix.DialOpt
+	user            string
+	password        string
+	selectDB        bool
+	db              int
+	commandsPerConn uint64
+	query           string
+	// record the command stats per endpoint, on -hosts
+	endpointStats bool
+}
+
+var connectLatencies = hdrhistogram.New(1, 90000000, 3)
+var authLatencies = hdrhistogram.New(1, 90000000, 3)
+var selectLatencies = hdrhistogram.New(1, 90000000, 3)
+var churnLatenciesMutex sync.Mutex
+
+var totalChurnConnections uint64
+var totalChurnConnectErrors uint64
+
+func recordChurnLatency(latencies *hdrhistogram.Histogram, duration time.Duration) {
+	churnLatenciesMutex.Lock()
+	defer churnLatenciesMutex.Unlock()
+	if err := latencies.RecordValue(duration.Microseconds()); err != nil {
+		log.Fatalf("Received an error while recording latencies: %v", err)
+	}
+}
+
+// newChurnCmd returns the -query command issued on the given key. The member is only used by the zscore query.
+func newChurnCmd(query string, keyname string, member string, r *rand.Rand) radix.CmdAction {
+	switch query {
+	case "zrange-byscore-rev":
+		return radix.Cmd(nil, "ZREVRANGEBYSCORE", keyname, "1", "0")
+	case "zrange-withscores":
+		return radix.Cmd(nil, "ZRANGE", keyname, "0", "1", "BYSCORE", "WITHSCORES")
+	case "zrevrangebylex":
+		return radix.Cmd(nil, "ZREVRANGEBYLEX", keyname, fmt.Sprintf("[%c", charset[r.Intn(len(charset))]), "-")
+	case "zscore":
+		return radix.Cmd(nil, "ZSCORE", keyname, member)
+	}
+	return radix.Cmd(nil, "ZRANGE", keyname, "0", "1", "BYSCORE")
+}
+
+// churnConnect dials a new connection, and issues AUTH and SELECT when configured,
+// recording the latency of each step.
+func churnConnect(config churnConfig) (radix.Conn, error) {
+	startT := time.Now()
+	conn, err := radix.Dial(config.network, config.addr, config.opts...)
+	if err != nil {
+		return nil, err
+	}
+	recordChurnLatency(connectLatencies, time.Since(startT))
+	if config.password != "" || config.user != "" {
+		authArgs := []string{config.password}
+		if config.user != "" {
+			authArgs = []string{config.user, config.password}
+		}
+		startT = time.Now()
+		if err = conn.Do(radix.Cmd(nil, "AUTH", authArgs...)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		recordChurnLatency(authLatencies, time.Since(startT))
+	}
+	if config.selectDB {
+		startT = time.Now()
+		if err = conn.Do(radix.Cmd(nil, "SELECT", strconv.Itoa(config.db))); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		recordChurnLatency(selectLatencies, time.Since(startT))
+	}
+	return conn, nil
+}
+
+func churnGoRoutime(config churnConfig, keyspace_start uint64, samplesPerClient uint64, debug int, w *sync.WaitGroup, useRateLimiter bool, rateLimiter *rate.Limiter, seed int64, keyConfig keyGeneratorConfig) {
+	defer w.Done()
+
+	r := rand.New(rand.NewSource(seed))
+	keys := newKeyGenerator(keyConfig, r)
+
+	var i uint64 = 0
+	for i < samplesPerClient {
+		connCommands := config.commandsPerConn
+		if samplesPerClient-i < connCommands {
+			connCommands = samplesPerClient - i
+		}
+		conn, err := churnConnect(config)
+		if err != nil {
+			// the commands of a failed connection are accounted as errors, so that the benchmark still ends
+			atomic.AddUint64(&totalChurnConnectErrors, 1)
+			recordCommandErrors(connCommands, nil, err, debug)
+			atomic.AddUint64(&totalCommands, connCommands)
+			i = i + connCommands
+			continue
+		}
+		var j uint64 = 0
+		for j < connCommands {
+			if useRateLimiter {
+				r := rateLimiter.ReserveN(time.Now(), 1)
+				time.Sleep(r.Delay())
+			}
+			keyname := getBenchKeyName(keyspace_start + keys.next())
+			var member string
+			err = nil
+			if config.query == "zscore" {
+				// the member is picked ahead of the timed command, so that the queried score exists
+				err = conn.Do(radix.Cmd(&member, "ZRANDMEMBER", keyname))
+			}
+			cmd := newChurnCmd(config.query, keyname, member, r)
+			var duration time.Duration
+			if err == nil {
+				startT := time.Now()
+				err = conn.Do(cmd)
+				duration = time.Since(startT)
+			}
+			if config.endpointStats {
+				recordEndpointStats(config.addr, 1, duration.Microseconds(), err != nil)
+			}
+			atomic.AddUint64(&totalCommands, 1)
+			j = j + 1
+			if err != nil {
+				recordCommandErrors(1, []radix.CmdAction{cmd}, err, debug)
+				if !isTimeoutError(err) {
+					continue
+				}
+				// the connection is unusable after a timeout
+				atomic.AddUint64(&totalErrors, connCommands-j)
+				atomic.AddUint64(&totalCommands, connCommands-j)
+				j = connCommands
+				break
+			}
+			if err = latencies.RecordValue(duration.Microseconds()); err != nil {
+				log.Fatalf("Received an error while recording latencies: %v", err)
+			}
+		}
+		i = i + connCommands
+		conn.Close()
+		atomic.AddUint64(&totalChurnConnections, 1)
+	}
+}
+
+// churnResults is the -mode=churn section of the benchmark results.
+type churnResults struct {
+	CommandsPerConnection uint64
+	Connections           uint64
+	ConnectErrors         uint64
+	ConnectionsPerSec     float64
+	ConnectLatencyMsec    map[string]float64
+	AuthLatencyMsec       map[string]float64 `json:",omitempty"`
+	SelectLatencyMsec     map[string]float64 `json:",omitempty"`
+}
+
+func getChurnResults(config churnConfig, duration time.Duration) *churnResults {
+	churnLatenciesMutex.Lock()
+	defer churnLatenciesMutex.Unlock()
+	results := &churnResults{
+		CommandsPerConnection: config.commandsPerConn,
+		Connections:           atomic.LoadUint64(&totalChurnConnections),
+		ConnectErrors:         atomic.LoadUint64(&totalChurnConnectErrors),
+		ConnectLatencyMsec:    latencyQuantilesMsec(connectLatencies),
+	}
+	results.ConnectionsPerSec = float64(results.Connections) / duration.Seconds()
+	if authLatencies.TotalCount() > 0 {
+		results.AuthLatencyMsec = latencyQuantilesMsec(authLatencies)
+	}
+	if selectLatencies.TotalCount() > 0 {
+		results.SelectLatencyMsec = latencyQuantilesMsec(selectLatencies)
+	}
+	return results
+}
+
+func printChurnSummary(results *churnResults) {
+	fmt.Printf("#################################################\n")
+	fmt.Printf("Connection churn. %d commands per connection\n", results.CommandsPerConnection)
+	fmt.Printf("Total connections %d (%.0f per second). Connect errors %d\n", results.Connections, results.ConnectionsPerSec, results.ConnectErrors)
+	fmt.Printf("Connection setup latency summary (msec):\n")
+	fmt.Printf("    %-8s %9s %9s %9s\n", "", "p50", "p95", "p99")
+	for _, step := range []struct {
+		name      string
+		latencies map[string]float64
+	}{{"connect", results.ConnectLatencyMsec}, {"auth", results.AuthLatencyMsec}, {"select", results.SelectLatencyMsec}} {
+		if step.latencies == nil {
+			continue
+		}
+		fmt.Printf("    %-8s %9.3f %9.3f %9.3f\n", step.name, step.latencies["p50"], step.latencies["p95"], step.latencies["p99"])
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// clientUsage is a snapshot of the benchmark process resource usage.
+// It lets us confirm that the client is not the limiting factor of a run.
+type clientUsage struct {
+	userCPU    time.Duration
+	systemCPU  time.Duration
+	mallocs    uint64
+	totalAlloc uint64
+	numGC      uint32
+	gcPause    time.Duration
+}
+
+func getClientUsage() clientUsage {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	userCPU, systemCPU := getProcessCPUTime()
+	return clientUsage{
+		userCPU:    userCPU,
+		systemCPU:  systemCPU,
+		mallocs:    memStats.Mallocs,
+		totalAlloc: memStats.TotalAlloc,
+		numGC:      memStats.NumGC,
+		gcPause:    time.Duration(memStats.PauseTotalNs),
+	}
+}
+
+func printClientUsage(start, end clientUsage, duration time.Duration, commands uint64) {
+	cpu := (end.userCPU - start.userCPU) + (end.systemCPU - start.systemCPU)
+	cpuPercent := 0.0
+	if duration > 0 {
+		cpuPercent = cpu.Seconds() / duration.Seconds() * 100.0
+	}
+	mallocs := end.mallocs - start.mallocs
+	allocatedBytes := end.totalAlloc - start.totalAlloc
+	perCommand := func(v uint64) float64 {
+		if commands == 0 {
+			return 0
+		}
+		return float64(v) / float64(commands)
+	}
+	fmt.Printf("#################################################\n")
+	fmt.Printf("Client resource usage\n")
+	fmt.Printf("CPU time: user %.3f sec, system %.3f sec. %.1f%% of one core (%d cores available)\n",
+		(end.userCPU - start.userCPU).Seconds(), (end.systemCPU - start.systemCPU).Seconds(), cpuPercent, runtime.NumCPU())
+	fmt.Printf("Allocations: %d (%.1f per command). Allocated bytes: %d (%.1f per command)\n",
+		mallocs, perCommand(mallocs), allocatedBytes, perCommand(allocatedBytes))
+	fmt.Printf("GC cycles: %d. GC total pause: %.3f msec\n", end.numGC-start.numGC, float64((end.gcPause-start.gcPause).Microseconds())/1000.0)
+	if cpuPercent > 90.0*float64(runtime.NumCPU()) {
+		fmt.Printf("WARNING: the client CPU was saturated during the run. The benchmark tool might be the limiting factor.\n")
+	}
+}
@@ -0,0 +1,25 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"syscall"
+	"time"
+)
+
+func getProcessCPUTime() (user time.Duration, system time.Duration) {
+	var creation, exit, kernel, userTime syscall.Filetime
+	handle, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0, 0
+	}
+	if err = syscall.GetProcessTimes(handle, &creation, &exit, &kernel, &userTime); err != nil {
+		return 0, 0
+	}
+	// Filetime values are expressed in 100-nanosecond intervals
+	toDuration := func(ft syscall.Filetime) time.Duration {
+		return time.Duration((int64(ft.HighDateTime)<<32 | int64(ft.LowDateTime)) * 100)
+	}
+	return toDuration(userTime), toDuration(kernel)
+}
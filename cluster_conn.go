@@ -3,31 +3,76 @@ package main
 import (
 	"github.com/mediocregopher/radix/v3"
 	"log"
+	"time"
 )
 
-func getOSSClusterConn(addr string, opts []radix.DialOpt, clients uint64) *radix.Cluster {
+// getOSSClusterConn connects to the cluster via the seed addresses, with a pool of clients connections per node.
+// With more than one seed the startup succeeds as long as one of the seeds is reachable.
+// With readOnly every connection issues READONLY, allowing reads to be served by the replicas.
+func getOSSClusterConn(seedAddrs []string, opts []radix.DialOpt, clients uint64, syncInterval time.Duration, readOnly bool, poolOpts ...radix.PoolOpt) *radix.Cluster {
 	var vanillaCluster *radix.Cluster
 	var err error
 
 	customConnFunc := func(network, addr string) (radix.Conn, error) {
-		return radix.Dial(network, addr, opts...,
-		)
+		conn, err := dialBenchConnWithBackoff(network, addr, opts)
+		if err != nil || !readOnly {
+			return conn, err
+		}
+		if err = conn.Do(radix.Cmd(nil, "READONLY")); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
 	}
 
 	// this cluster will use the ClientFunc to create a pool to each node in the
 	// cluster.
 	poolFunc := func(network, addr string) (radix.Client, error) {
-		return radix.NewPool(network, addr, int(clients), radix.PoolConnFunc(customConnFunc), radix.PoolPipelineWindow(0, 0))
+		return radix.NewPool(network, addr, int(clients), append([]radix.PoolOpt{radix.PoolConnFunc(customConnFunc), radix.PoolPipelineWindow(0, 0)}, poolOpts...)...)
 	}
 
-	vanillaCluster, err = radix.NewCluster([]string{addr}, radix.ClusterPoolFunc(poolFunc))
+	allowUnavailable := len(seedAddrs) > 1
+	vanillaCluster, err = radix.NewCluster(seedAddrs, radix.ClusterPoolFunc(poolFunc), radix.ClusterSyncEvery(syncInterval), radix.ClusterWithTrace(newClusterTrace()), radix.ClusterOnInitAllowUnavailable(allowUnavailable))
 	if err != nil {
-		log.Fatalf("Error preparing for benchmark, while creating new connection to %v. error = %v", []string{addr}, err)
+		log.Fatalf("Error preparing for benchmark, while creating new connection to %v. error = %v", seedAddrs, err)
 	}
 	// Issue CLUSTER SLOTS command
 	err = vanillaCluster.Sync()
 	if err != nil {
-		log.Fatalf("Error preparing for benchmark, while issuing CLUSTER SLOTS. error = %v", err)
+		if !allowUnavailable {
+			log.Fatalf("Error preparing for benchmark, while issuing CLUSTER SLOTS. error = %v", err)
+		}
+		log.Printf("Some of the cluster nodes are unavailable: %v", err)
 	}
 	return vanillaCluster
 }
+
+func getClusterPrimaryAddrForSlot(cluster *radix.Cluster, slot uint16) string {
+	addr, _ := getPrimaryForSlot(cluster.Topo().Primaries(), slot)
+	return addr
+}
+
+// getPrimaryForSlot returns the address of the primary serving the slot, along with the
+// slot range (start inclusive, end exclusive) the slot belongs to.
+func getPrimaryForSlot(primaries radix.ClusterTopo, slot uint16) (string, [2]uint16) {
+	for _, node := range primaries {
+		for _, slots := range node.Slots {
+			if slot >= slots[0] && slot < slots[1] {
+				return node.Addr, slots
+			}
+		}
+	}
+	return "", [2]uint16{}
+}
+
+// getClientForKey returns the client that owns the given key. For cluster
+// connections this resolves the primary node holding the key slot, which is
+// required for commands that radix does not route by key (e.g. OBJECT).
+func getClientForKey(conn radix.Client, key string) (radix.Client, error) {
+	cluster, isCluster := conn.(*radix.Cluster)
+	if !isCluster {
+		return conn, nil
+	}
+	slot := radix.ClusterSlot([]byte(key))
+	return cluster.Client(getClusterPrimaryAddrForSlot(cluster, slot))
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/mediocregopher/radix/v3"
+	"github.com/mediocregopher/radix/v3/resp/resp2"
+	"github.com/mediocregopher/radix/v3/trace"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// number of times a redirected command is re-issued before being counted as an error
+const clusterRedirectAttempts = 3
+
+var totalMovedRedirects uint64
+var totalAskRedirects uint64
+var totalRedirectedCommands uint64
+var totalTopologyRefreshes uint64
+
+var redirectLatencies = hdrhistogram.New(1, 90000000, 3)
+var redirectLatenciesMutex sync.Mutex
+
+// topologyEvent is a cluster topology, or cluster state, change observed during the benchmark.
+type topologyEvent struct {
+	Time    time.Time
+	Event   string
+	Added   []string `json:",omitempty"`
+	Removed []string `json:",omitempty"`
+	Changed []string `json:",omitempty"`
+}
+
+var topologyEvents []topologyEvent
+var topologyEventsMutex sync.Mutex
+
+func recordTopologyEvent(event topologyEvent) {
+	topologyEventsMutex.Lock()
+	defer topologyEventsMutex.Unlock()
+	topologyEvents = append(topologyEvents, event)
+}
+
+func clusterNodeInfoAddrs(nodes []trace.ClusterNodeInfo) []string {
+	addrs := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		role := "replica"
+		if node.IsPrimary {
+			role = "primary"
+		}
+		addrs = append(addrs, fmt.Sprintf("%s (%s, %d slot ranges)", node.Addr, role, len(node.Slots)))
+	}
+	return addrs
+}
+
+// newClusterTrace records the topology changes, cluster state changes, and the redirects
+// followed by radix itself, e.g. while re-issuing redirected commands.
+func newClusterTrace() trace.ClusterTrace {
+	return trace.ClusterTrace{
+		TopoChanged: func(changed trace.ClusterTopoChanged) {
+			recordTopologyEvent(topologyEvent{
+				Time:    time.Now(),
+				Event:   "topology changed",
+				Added:   clusterNodeInfoAddrs(changed.Added),
+				Removed: clusterNodeInfoAddrs(changed.Removed),
+				Changed: clusterNodeInfoAddrs(changed.Changed),
+			})
+		},
+		StateChange: func(change trace.ClusterStateChange) {
+			event := "cluster available"
+			if change.IsDown {
+				event = "cluster down"
+			}
+			recordTopologyEvent(topologyEvent{Time: time.Now(), Event: event})
+		},
+		Redirected: func(redirected trace.ClusterRedirected) {
+			if redirected.Moved {
+				atomic.AddUint64(&totalMovedRedirects, 1)
+			}
+			if redirected.Ask {
+				atomic.AddUint64(&totalAskRedirects, 1)
+			}
+		},
+	}
+}
+
+// redirectCatcher wraps a command issued within a node pipeline. MOVED and ASK replies are
+// recorded instead of failing the pipeline, so that the command can be re-issued afterwards.
+// EXEC replies aborted because of a redirected queued command are swallowed as well.
+type redirectCatcher struct {
+	radix.CmdAction
+	group *slotGroup
+	moved bool
+	ask   bool
+}
+
+func (c *redirectCatcher) UnmarshalRESP(br *bufio.Reader) error {
+	err := c.CmdAction.UnmarshalRESP(br)
+	var respErr resp2.Error
+	if err == nil || !errors.As(err, &respErr) {
+		return err
+	}
+	msg := respErr.Error()
+	switch {
+	case strings.HasPrefix(msg, "MOVED "):
+		c.moved = true
+		c.group.redirected = true
+		return nil
+	case strings.HasPrefix(msg, "ASK "):
+		c.ask = true
+		c.group.redirected = true
+		return nil
+	case strings.HasPrefix(msg, "EXECABORT") && c.group.redirected:
+		return nil
+	}
+	return err
+}
+
+func (c *redirectCatcher) Run(conn radix.Conn) error {
+	if err := conn.Encode(c); err != nil {
+		return err
+	}
+	return conn.Decode(c)
+}
+
+// retryRedirected re-issues the redirected commands of a node pipeline, and returns the number
+// of commands that failed. Without multi each redirected command is re-issued via the cluster,
+// which follows further redirects, to a replica when readFromReplica is set. With multi the whole
+// transaction of a redirected slot is re-issued, after the slot map was refreshed.
+func retryRedirected(cluster *radix.Cluster, groups []*slotGroup, multi bool, attempts int) (uint64, error) {
+	var moved, ask, redirected uint64
+	for _, group := range groups {
+		if !group.redirected {
+			continue
+		}
+		for _, catcher := range group.catchers {
+			if catcher.moved {
+				moved++
+			}
+			if catcher.ask {
+				ask++
+			}
+			if catcher.moved || catcher.ask {
+				redirected++
+			}
+		}
+	}
+	if redirected == 0 {
+		return 0, nil
+	}
+	atomic.AddUint64(&totalMovedRedirects, moved)
+	atomic.AddUint64(&totalAskRedirects, ask)
+	atomic.AddUint64(&totalRedirectedCommands, redirected)
+	if moved > 0 {
+		atomic.AddUint64(&totalTopologyRefreshes, 1)
+		if err := cluster.Sync(); err != nil {
+			log.Printf("Received an error while refreshing the cluster slot map: %v", err)
+		}
+	}
+
+	startT := time.Now()
+	var failed uint64 = 0
+	var firstErr error
+	for _, group := range groups {
+		if !group.redirected {
+			continue
+		}
+		if multi {
+			groupFailed, err := doClusterPipeline(cluster, group.cmds, true, attempts-1)
+			failed += groupFailed
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, catcher := range group.catchers {
+			if !catcher.moved && !catcher.ask {
+				continue
+			}
+			var err error
+			if readFromReplica {
+				err = cluster.DoSecondary(catcher.CmdAction)
+			} else {
+				err = cluster.Do(catcher.CmdAction)
+			}
+			if err != nil {
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+	}
+	recordRedirectLatency(time.Since(startT).Microseconds())
+	return failed, firstErr
+}
+
+func recordRedirectLatency(durationMicros int64) {
+	redirectLatenciesMutex.Lock()
+	defer redirectLatenciesMutex.Unlock()
+	if err := redirectLatencies.RecordValue(durationMicros); err != nil {
+		log.Fatalf("Received an error while recording latencies: %v", err)
+	}
+}
+
+// clusterRedirectResults is the redirects and topology changes section of the benchmark results.
+type clusterRedirectResults struct {
+	MovedRedirects        uint64
+	AskRedirects          uint64
+	RedirectedCommands    uint64
+	TopologyRefreshes     uint64
+	PenaltyLatencySamples int64
+	PenaltyLatencyMsec    map[string]float64
+	TopologyEvents        []topologyEvent
+}
+
+func getClusterRedirectResults() *clusterRedirectResults {
+	redirectLatenciesMutex.Lock()
+	defer redirectLatenciesMutex.Unlock()
+	topologyEventsMutex.Lock()
+	defer topologyEventsMutex.Unlock()
+	return &clusterRedirectResults{
+		MovedRedirects:        atomic.LoadUint64(&totalMovedRedirects),
+		AskRedirects:          atomic.LoadUint64(&totalAskRedirects),
+		RedirectedCommands:    atomic.LoadUint64(&totalRedirectedCommands),
+		TopologyRefreshes:     atomic.LoadUint64(&totalTopologyRefreshes),
+		PenaltyLatencyMsec:    latencyQuantilesMsec(redirectLatencies),
+		PenaltyLatencySamples: redirectLatencies.TotalCount(),
+		TopologyEvents:        append([]topologyEvent{}, topologyEvents...),
+	}
+}
+
+func printClusterRedirectSummary(results *clusterRedirectResults, start time.Time, totalCommands uint64) {
+	fmt.Printf("#################################################\n")
+	fmt.Printf("Cluster redirects and topology changes\n")
+	redirectedPercent := 0.0
+	if totalCommands > 0 {
+		redirectedPercent = float64(results.RedirectedCommands) / float64(totalCommands) * 100.0
+	}
+	fmt.Printf("MOVED redirects %d. ASK redirects %d. Redirected commands %d (%.3f%%)\n", results.MovedRedirects, results.AskRedirects, results.RedirectedCommands, redirectedPercent)
+	fmt.Printf("Slot map refreshes triggered by MOVED redirects %d\n", results.TopologyRefreshes)
+	if results.PenaltyLatencySamples > 0 {
+		fmt.Printf("Redirect latency penalty (msec):\n")
+		fmt.Printf("    %9s %9s %9s\n", "p50", "p95", "p99")
+		fmt.Printf("    %9.3f %9.3f %9.3f\n", results.PenaltyLatencyMsec["p50"], results.PenaltyLatencyMsec["p95"], results.PenaltyLatencyMsec["p99"])
+	}
+	for _, event := range results.TopologyEvents {
+		fmt.Printf("%s (%+.3f sec) %s", event.Time.Format(time.RFC3339), event.Time.Sub(start).Seconds(), event.Event)
+		if len(event.Added) > 0 {
+			fmt.Printf(". Added: %v", event.Added)
+		}
+		if len(event.Removed) > 0 {
+			fmt.Printf(". Removed: %v", event.Removed)
+		}
+		if len(event.Changed) > 0 {
+			fmt.Printf(". Changed: %v", event.Changed)
+		}
+		fmt.Printf("\n")
+	}
+}
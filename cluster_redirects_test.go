@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"github.com/mediocregopher/radix/v3"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const stubNodeA = "127.0.0.1:7000"
+const stubNodeB = "127.0.0.1:7001"
+
+// the last slot of node-a, which is redirected to node-b
+const stubRedirectedSlot = 8191
+
+// redirectingNodes replies as two cluster nodes where the redirected slot lives on node-b, while the
+// slot map still announces it on node-a. With "moved" the slot map announces it on node-b after the
+// first MOVED, with "ask" node-a replies ASK instead, and with "unsettled" both nodes keep replying
+// MOVED to each other.
+type redirectingNodes struct {
+	mode     string
+	mutex    sync.Mutex
+	migrated bool
+	log      map[string][]string
+	asking   map[string]bool
+	inMulti  map[string]bool
+	aborted  map[string]bool
+	queued   map[string]int
+}
+
+func newRedirectingNodes(mode string) *redirectingNodes {
+	return &redirectingNodes{
+		mode:    mode,
+		log:     map[string][]string{},
+		asking:  map[string]bool{},
+		inMulti: map[string]bool{},
+		aborted: map[string]bool{},
+		queued:  map[string]int{},
+	}
+}
+
+func (n *redirectingNodes) slots() []interface{} {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.migrated {
+		return stubClusterSlots(stubRedirectedSlot - 1)
+	}
+	return stubClusterSlots(stubRedirectedSlot)
+}
+
+func (n *redirectingNodes) reply(addr string, args []string) interface{} {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.log[addr] = append(n.log[addr], strings.Join(args, " "))
+	asking := n.asking[addr]
+	n.asking[addr] = false
+	switch strings.ToUpper(args[0]) {
+	case "ASKING":
+		n.asking[addr] = true
+		return "OK"
+	case "MULTI":
+		n.inMulti[addr], n.aborted[addr], n.queued[addr] = true, false, 0
+		return "OK"
+	case "EXEC":
+		n.inMulti[addr] = false
+		if n.aborted[addr] {
+			return errors.New("EXECABORT Transaction discarded because of previous errors.")
+		}
+		replies := []interface{}{}
+		for i := 0; i < n.queued[addr]; i++ {
+			replies = append(replies, []string{addr})
+		}
+		return replies
+	}
+	var reply interface{} = []string{addr}
+	if radix.ClusterSlot([]byte(args[1])) == stubRedirectedSlot {
+		switch {
+		case addr == stubNodeA && n.mode == "ask":
+			reply = errors.New("ASK 8191 " + stubNodeB)
+		case addr == stubNodeA:
+			n.migrated = n.mode == "moved"
+			reply = errors.New("MOVED 8191 " + stubNodeB)
+		case n.mode == "unsettled" || n.mode == "ask" && !asking:
+			reply = errors.New("MOVED 8191 " + stubNodeA)
+		}
+	}
+	if n.inMulti[addr] {
+		if _, isErr := reply.(error); isErr {
+			n.aborted[addr] = true
+			return reply
+		}
+		n.queued[addr]++
+		return "QUEUED"
+	}
+	return reply
+}
+
+func TestDoClusterPipelineRedirects(t *testing.T) {
+	redirectedA := slotKey(stubRedirectedSlot, "a")
+	redirectedB := slotKey(stubRedirectedSlot, "b")
+	notRedirected := slotKey(1, "c")
+	tests := []struct {
+		name  string
+		mode  string
+		multi bool
+		keys  []string
+		// node expected to serve each key, or empty when the command fails
+		wantNodes     []string
+		wantFailed    uint64
+		wantErr       string
+		wantMoved     uint64
+		wantAsk       uint64
+		wantRefreshes uint64
+		wantNodeBCmds []string
+	}{
+		{"MOVED retried through the cluster", "moved", false, []string{redirectedA, notRedirected}, []string{stubNodeB, stubNodeA}, 0, "", 1, 0, 1,
+			[]string{"ZRANGE " + redirectedA + " 0 1 BYSCORE"}},
+		{"ASK retried through the cluster", "ask", false, []string{redirectedA, notRedirected}, []string{stubNodeB, stubNodeA}, 0, "", 0, 1, 0,
+			[]string{"ASKING", "ZRANGE " + redirectedA + " 0 1 BYSCORE"}},
+		{"MOVED slot transaction re-issued whole", "moved", true, []string{redirectedA, notRedirected, redirectedB}, []string{stubNodeB, stubNodeA, stubNodeB}, 0, "", 2, 0, 1,
+			[]string{"MULTI", "ZRANGE " + redirectedA + " 0 1 BYSCORE", "ZRANGE " + redirectedB + " 0 1 BYSCORE", "EXEC"}},
+		{"retry limit reached", "unsettled", false, []string{redirectedA, notRedirected}, []string{"", stubNodeA}, 1, "redirected too many times", 1, 0, 1, nil},
+		{"retry limit reached on multi", "unsettled", true, []string{redirectedA, notRedirected}, []string{"", stubNodeA}, 1, "still redirected after 3 attempts", 3, 0, 3, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := newRedirectingNodes(tt.mode)
+			cluster := newStubCluster(t, nodes.slots, nodes.reply)
+			replies := make([][]string, len(tt.keys))
+			cmds := []radix.CmdAction{}
+			for i, key := range tt.keys {
+				cmds = append(cmds, radix.Cmd(&replies[i], "ZRANGE", key, "0", "1", "BYSCORE"))
+			}
+			moved, ask := atomic.LoadUint64(&totalMovedRedirects), atomic.LoadUint64(&totalAskRedirects)
+			redirected, refreshes := atomic.LoadUint64(&totalRedirectedCommands), atomic.LoadUint64(&totalTopologyRefreshes)
+
+			failed, err := doBenchPipeline(cluster, cmds, tt.multi)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("doBenchPipeline() error = %v, want %q", err, tt.wantErr)
+			}
+			if failed != tt.wantFailed {
+				t.Errorf("doBenchPipeline() failed = %d, want %d", failed, tt.wantFailed)
+			}
+			for i, node := range tt.wantNodes {
+				if node != "" && !reflect.DeepEqual(replies[i], []string{node}) {
+					t.Errorf("key %s reply = %v, want it served by %s", tt.keys[i], replies[i], node)
+				}
+			}
+			if got := atomic.LoadUint64(&totalMovedRedirects) - moved; got != tt.wantMoved {
+				t.Errorf("MOVED redirects = %d, want %d", got, tt.wantMoved)
+			}
+			if got := atomic.LoadUint64(&totalAskRedirects) - ask; got != tt.wantAsk {
+				t.Errorf("ASK redirects = %d, want %d", got, tt.wantAsk)
+			}
+			if got := atomic.LoadUint64(&totalRedirectedCommands) - redirected; got != tt.wantMoved+tt.wantAsk {
+				t.Errorf("redirected commands = %d, want %d", got, tt.wantMoved+tt.wantAsk)
+			}
+			if got := atomic.LoadUint64(&totalTopologyRefreshes) - refreshes; got != tt.wantRefreshes {
+				t.Errorf("slot map refreshes = %d, want %d", got, tt.wantRefreshes)
+			}
+			if tt.wantNodeBCmds != nil && !reflect.DeepEqual(nodes.log[stubNodeB], tt.wantNodeBCmds) {
+				t.Errorf("node-b received %q, want %q", nodes.log[stubNodeB], tt.wantNodeBCmds)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// shardStats holds the stats of the commands issued to one slot range of a cluster node.
+type shardStats struct {
+	addr      string
+	slotRange [2]uint16
+	commands  uint64
+	errors    uint64
+	latencies *hdrhistogram.Histogram
+}
+
+type shardStatsKey struct {
+	addr      string
+	slotRange [2]uint16
+}
+
+var shards = map[shardStatsKey]*shardStats{}
+var shardsMutex sync.Mutex
+
+func recordShardStats(batch *nodeBatch, durationMicros int64, failed bool) {
+	shardsMutex.Lock()
+	defer shardsMutex.Unlock()
+	for slotRange, commands := range batch.slotRangeCommands {
+		key := shardStatsKey{addr: batch.addr, slotRange: slotRange}
+		stats, found := shards[key]
+		if !found {
+			stats = &shardStats{addr: batch.addr, slotRange: slotRange, latencies: hdrhistogram.New(1, 90000000, 3)}
+			shards[key] = stats
+		}
+		stats.commands += commands
+		if failed {
+			stats.errors += commands
+			continue
+		}
+		if err := stats.latencies.RecordValue(durationMicros); err != nil {
+			log.Fatalf("Received an error while recording latencies: %v", err)
+		}
+	}
+}
+
+// shardResults is the per node, or per slot range, breakdown of the benchmark results.
+type shardResults struct {
+	Node        string
+	SlotRanges  []string `json:",omitempty"`
+	Commands    uint64
+	Errors      uint64
+	OpsPerSec   float64
+	LatencyMsec map[string]float64
+}
+
+func newShardResults(addr string, commands, errors uint64, latencies *hdrhistogram.Histogram, duration time.Duration) shardResults {
+	opsPerSec := 0.0
+	if duration > 0 {
+		opsPerSec = float64(commands) / duration.Seconds()
+	}
+	return shardResults{
+		Node:        addr,
+		Commands:    commands,
+		Errors:      errors,
+		OpsPerSec:   opsPerSec,
+		LatencyMsec: latencyQuantilesMsec(latencies),
+	}
+}
+
+func latencyQuantilesMsec(latencies *hdrhistogram.Histogram) map[string]float64 {
+	return map[string]float64{
+		"p50": float64(latencies.ValueAtQuantile(50.0)) / 1000.0,
+		"p95": float64(latencies.ValueAtQuantile(95.0)) / 1000.0,
+		"p99": float64(latencies.ValueAtQuantile(99.0)) / 1000.0,
+	}
+}
+
+func formatSlotRange(slotRange [2]uint16) string {
+	return fmt.Sprintf("%d-%d", slotRange[0], int(slotRange[1])-1)
+}
+
+// getShardResults returns the results broken down per node and per slot range, sorted by node address
+// and slot range start.
+func getShardResults(duration time.Duration) (nodes []shardResults, slotRanges []shardResults) {
+	shardsMutex.Lock()
+	defer shardsMutex.Unlock()
+	keys := make([]shardStatsKey, 0, len(shards))
+	for key := range shards {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].addr != keys[j].addr {
+			return keys[i].addr < keys[j].addr
+		}
+		return keys[i].slotRange[0] < keys[j].slotRange[0]
+	})
+	for _, key := range keys {
+		stats := shards[key]
+		slotRange := newShardResults(stats.addr, stats.commands, stats.errors, stats.latencies, duration)
+		slotRange.SlotRanges = []string{formatSlotRange(stats.slotRange)}
+		slotRanges = append(slotRanges, slotRange)
+		if len(nodes) == 0 || nodes[len(nodes)-1].Node != stats.addr {
+			nodeLatencies := hdrhistogram.New(1, 90000000, 3)
+			var commands, errors uint64
+			nodeSlotRanges := []string{}
+			for _, other := range keys {
+				if other.addr == stats.addr {
+					nodeLatencies.Merge(shards[other].latencies)
+					commands += shards[other].commands
+					errors += shards[other].errors
+					nodeSlotRanges = append(nodeSlotRanges, formatSlotRange(other.slotRange))
+				}
+			}
+			node := newShardResults(stats.addr, commands, errors, nodeLatencies, duration)
+			node.SlotRanges = nodeSlotRanges
+			nodes = append(nodes, node)
+		}
+	}
+	return
+}
+
+func printShardSummary(nodes []shardResults, slotRanges []shardResults) {
+	if len(nodes) == 0 {
+		return
+	}
+	fmt.Printf("#################################################\n")
+	fmt.Printf("Summary by node (latency in msec):\n")
+	printShardResultsTable(nodes)
+	fmt.Printf("Summary by slot range (latency in msec):\n")
+	printShardResultsTable(slotRanges)
+}
+
+func printShardResultsTable(results []shardResults) {
+	fmt.Printf("    %21s %13s %11s %11s %9s %9s %9s %9s\n", "node", "slots", "commands", "ops/sec", "errors", "p50", "p95", "p99")
+	for _, result := range results {
+		slotRanges := fmt.Sprintf("%d ranges", len(result.SlotRanges))
+		if len(result.SlotRanges) == 1 {
+			slotRanges = result.SlotRanges[0]
+		}
+		fmt.Printf("    %21s %13s %11d %11.0f %9d %9.3f %9.3f %9.3f\n", result.Node, slotRanges, result.Commands, result.OpsPerSec, result.Errors,
+			result.LatencyMsec["p50"], result.LatencyMsec["p95"], result.LatencyMsec["p99"])
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mediocregopher/radix/v3"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+const connModelPool = "pool"
+const connModelDedicated = "dedicated"
+const connModelShared = "shared"
+
+var connModels = []string{connModelPool, connModelDedicated, connModelShared}
+
+// benchClientName is set via CLIENT SETNAME on every benchmark connection,
+// so that the server side connections can be told apart on CLIENT LIST.
+// The pid suffix keeps apart the connections of concurrent runs against the same server.
+var benchClientName = fmt.Sprintf("redis-zbench-go-%d", os.Getpid())
+
+// connModelPoolOpts returns the options of the pools backing the dedicated and shared
+// connection models, which block until a connection is available instead of dialing
+// additional connections, so that the number of connections is fixed.
+func connModelPoolOpts(model string) []radix.PoolOpt {
+	if model == connModelPool {
+		return nil
+	}
+	return []radix.PoolOpt{radix.PoolOnEmptyWait()}
+}
+
+// connModelNodeConns returns the number of connections per server node of the connection model.
+// The pool model starts with one connection per client, and dials additional ones on demand.
+func connModelNodeConns(model string, clients uint64, clientsPerConn uint64) uint64 {
+	if model == connModelShared {
+		return (clients + clientsPerConn - 1) / clientsPerConn
+	}
+	return clients
+}
+
+// clientConns hands out the connection used by each client goroutine. With the pool model all
+// clients share the given pool. With the dedicated model each client gets its own connection,
+// and with the shared model each group of clientsPerConn clients shares a single connection.
+// The dedicated and shared connections are backed by single connection pools, which reconnect on failures.
+type clientConns struct {
+	model          string
+	clientsPerConn uint64
+	opts           []radix.DialOpt
+	shared         map[string]radix.Client
+}
+
+func newClientConns(model string, clientsPerConn uint64, opts []radix.DialOpt) *clientConns {
+	return &clientConns{model: model, clientsPerConn: clientsPerConn, opts: opts, shared: map[string]radix.Client{}}
+}
+
+func (c *clientConns) get(clientID uint64, network, addr string, pool radix.Client) radix.Client {
+	switch c.model {
+	case connModelDedicated:
+		return getStandaloneConn(network, addr, c.opts, 1, connModelPoolOpts(c.model)...)
+	case connModelShared:
+		group := fmt.Sprintf("%s/%d", addr, (clientID-1)/c.clientsPerConn)
+		conn, found := c.shared[group]
+		if !found {
+			conn = getStandaloneConn(network, addr, c.opts, 1, connModelPoolOpts(c.model)...)
+			c.shared[group] = conn
+		}
+		return conn
+	}
+	return pool
+}
+
+// countServerConns returns the number of benchmark connections on the server, out of CLIENT LIST.
+func countServerConns(client radix.Client) (int, error) {
+	var clientList string
+	if err := client.Do(radix.Cmd(&clientList, "CLIENT", "LIST")); err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, line := range strings.Split(clientList, "\n") {
+		for _, field := range strings.Fields(line) {
+			if field == "name="+benchClientName {
+				count++
+				break
+			}
+		}
+	}
+	return count, nil
+}
+
+// getServerConns returns the number of benchmark connections per server node.
+func getServerConns(conn radix.Client, addr string) map[string]int {
+	serverConns := map[string]int{}
+	cluster, isCluster := conn.(*radix.Cluster)
+	if !isCluster {
+		count, err := countServerConns(conn)
+		if err != nil {
+			log.Printf("Received an error while issuing CLIENT LIST on %s: %v", addr, err)
+			return serverConns
+		}
+		serverConns[addr] = count
+		return serverConns
+	}
+	for _, node := range cluster.Topo() {
+		client, err := cluster.Client(node.Addr)
+		if err != nil {
+			log.Printf("Unable to get a client for node %s: %v", node.Addr, err)
+			continue
+		}
+		count, err := countServerConns(client)
+		if err != nil {
+			log.Printf("Received an error while issuing CLIENT LIST on %s: %v", node.Addr, err)
+			continue
+		}
+		serverConns[node.Addr] = count
+	}
+	return serverConns
+}
+
+func printServerConnsSummary(model string, expected uint64, serverConns map[string]int) {
+	fmt.Printf("#################################################\n")
+	fmt.Printf("Server side connections (CLIENT LIST, name=%s). Connection model: %s\n", benchClientName, model)
+	addrs := make([]string, 0, len(serverConns))
+	for addr := range serverConns {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	total := 0
+	for _, addr := range addrs {
+		fmt.Printf("    %-25s %d\n", addr, serverConns[addr])
+		total += serverConns[addr]
+	}
+	fmt.Printf("Total benchmark connections %d. Expected %d\n", total, expected)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/mediocregopher/radix/v3"
+	"log"
+	"math/rand"
+	"strconv"
+	"sync"
+)
+
+const encodingListpack = "listpack"
+const encodingSkiplist = "skiplist"
+
+var encodingLatencies map[string]*hdrhistogram.Histogram
+var encodingLatenciesMutex sync.Mutex
+
+type zsetEncodingThresholds struct {
+	maxEntries uint64
+	maxValue   uint64
+}
+
+// getZsetEncodingThresholds reads the sorted set listpack thresholds from the server.
+// Servers older than 7.0 only know the ziplist-named configs, so we fallback to those.
+func getZsetEncodingThresholds(conn radix.Client) *zsetEncodingThresholds {
+	thresholds := &zsetEncodingThresholds{}
+	thresholds.maxEntries = getZsetEncodingConfig(conn, "zset-max-listpack-entries", "zset-max-ziplist-entries")
+	thresholds.maxValue = getZsetEncodingConfig(conn, "zset-max-listpack-value", "zset-max-ziplist-value")
+	return thresholds
+}
+
+func getZsetEncodingConfig(conn radix.Client, names ...string) uint64 {
+	for _, name := range names {
+		var reply map[string]string
+		err := conn.Do(radix.Cmd(&reply, "CONFIG", "GET", name))
+		if err != nil {
+			log.Fatalf("Error preparing for benchmark, while issuing CONFIG GET %s. error = %v", name, err)
+		}
+		if value, found := reply[name]; found {
+			parsed, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				log.Fatalf("Error preparing for benchmark, unable to parse %s value %s. error = %v", name, value, err)
+			}
+			return parsed
+		}
+	}
+	log.Fatalf("Error preparing for benchmark, none of the following configs are available: %v", names)
+	return 0
+}
+
+// expectedEncoding returns the encoding a key is generated for.
+// Even keys stay just below the thresholds while odd keys are pushed just above them.
+func (t *zsetEncodingThresholds) expectedEncoding(keypos uint64) string {
+	if keypos%2 == 0 {
+		return encodingListpack
+	}
+	return encodingSkiplist
+}
+
+// keyShape returns the number of elements and the element data size for the given key.
+// Skiplist keys alternate between crossing the entries threshold and the value threshold.
+func (t *zsetEncodingThresholds) keyShape(keypos uint64, perKeyElmDataSize uint64) (nElements int64, dataSize uint64) {
+	nElements = int64(t.maxEntries)
+	if nElements < 1 {
+		nElements = 1
+	}
+	dataSize = perKeyElmDataSize
+	if dataSize > t.maxValue {
+		dataSize = t.maxValue
+	}
+	if t.expectedEncoding(keypos) == encodingSkiplist {
+		if (keypos/2)%2 == 0 {
+			nElements = int64(t.maxEntries) + 1
+		} else {
+			dataSize = t.maxValue + 1
+		}
+	}
+	return
+}
+
+func newEncodingLatencies() map[string]*hdrhistogram.Histogram {
+	return map[string]*hdrhistogram.Histogram{
+		encodingListpack: hdrhistogram.New(1, 90000000, 3),
+		encodingSkiplist: hdrhistogram.New(1, 90000000, 3),
+	}
+}
+
+func recordEncodingLatency(encoding string, durationMicros int64) {
+	encodingLatenciesMutex.Lock()
+	defer encodingLatenciesMutex.Unlock()
+	err := encodingLatencies[encoding].RecordValue(durationMicros)
+	if err != nil {
+		log.Fatalf("Received an error while recording latencies: %v", err)
+	}
+}
+
+// normalizeEncoding maps the pre 7.0 ziplist encoding name to its listpack equivalent.
+func normalizeEncoding(encoding string) string {
+	if encoding == "ziplist" {
+		return encodingListpack
+	}
+	return encoding
+}
+
+func verifyEncodings(conn radix.Client, thresholds *zsetEncodingThresholds, keyspaceStart, keyspaceLen, samples uint64, seed int64) {
+	r := rand.New(rand.NewSource(seed))
+	var matched, mismatched, missing uint64
+	sampledEncodings := map[string]uint64{}
+	for i := uint64(0); i < samples && keyspaceLen > 0; i++ {
+		keypos := keyspaceStart + uint64(r.Int63n(int64(keyspaceLen)))
+		keyname := getBenchKeyName(keypos)
+		client, err := getClientForKey(conn, keyname)
+		if err != nil {
+			log.Fatalf("Received an error while resolving the node for key %s: %v", keyname, err)
+		}
+		var encoding string
+		mn := radix.MaybeNil{Rcv: &encoding}
+		err = client.Do(radix.Cmd(&mn, "OBJECT", "ENCODING", keyname))
+		if err != nil {
+			// e.g. OBJECT not allowed by the ACL user, which does not invalidate the benchmark results
+			log.Printf("Unable to verify the encodings, received an error while issuing OBJECT ENCODING %s: %v", keyname, err)
+			return
+		}
+		if mn.Nil {
+			missing++
+			continue
+		}
+		encoding = normalizeEncoding(encoding)
+		sampledEncodings[encoding]++
+		if encoding == thresholds.expectedEncoding(keypos) {
+			matched++
+		} else {
+			mismatched++
+		}
+	}
+	fmt.Printf("#################################################\n")
+	fmt.Printf("Encoding verification on %d sampled keys\n", samples)
+	for encoding, count := range sampledEncodings {
+		fmt.Printf("Encoding: %s\tCount: %d\n", encoding, count)
+	}
+	fmt.Printf("Matched expected encoding %d. Mismatched %d. Missing keys %d\n", matched, mismatched, missing)
+}
+
+func printEncodingLatencySummary() {
+	encodingLatenciesMutex.Lock()
+	defer encodingLatenciesMutex.Unlock()
+	fmt.Printf("Latency summary by encoding (msec):\n")
+	fmt.Printf("    %9s %9s %9s %9s %9s\n", "encoding", "count", "p50", "p95", "p99")
+	for _, encoding := range []string{encodingListpack, encodingSkiplist} {
+		histogram := encodingLatencies[encoding]
+		fmt.Printf("    %9s %9d %9.3f %9.3f %9.3f\n", encoding, histogram.TotalCount(),
+			float64(histogram.ValueAtQuantile(50.0))/1000.0,
+			float64(histogram.ValueAtQuantile(95.0))/1000.0,
+			float64(histogram.ValueAtQuantile(99.0))/1000.0)
+	}
+}
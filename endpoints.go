@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/mediocregopher/radix/v3"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// parseEndpointAddrs parses a comma separated list of host:port endpoint addresses.
+func parseEndpointAddrs(list string) ([]string, error) {
+	addrs := []string{}
+	seen := map[string]bool{}
+	for _, addr := range strings.Split(list, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if !strings.Contains(addr, ":") {
+			return nil, fmt.Errorf("invalid endpoint address %s. Expected host:port", addr)
+		}
+		if seen[addr] {
+			return nil, fmt.Errorf("duplicate endpoint address %s", addr)
+		}
+		seen[addr] = true
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("empty endpoint address list")
+	}
+	return addrs, nil
+}
+
+// endpointClient is the connection of a client to one of the standalone -hosts endpoints,
+// whose pipelines are accounted on the endpoint stats.
+type endpointClient struct {
+	radix.Client
+	addr string
+}
+
+// endpointStats holds the stats of the commands issued to one standalone endpoint.
+type endpointStats struct {
+	commands  uint64
+	errors    uint64
+	latencies *hdrhistogram.Histogram
+}
+
+var endpoints = map[string]*endpointStats{}
+var endpointsMutex sync.Mutex
+
+func recordEndpointStats(addr string, commands uint64, durationMicros int64, failed bool) {
+	endpointsMutex.Lock()
+	defer endpointsMutex.Unlock()
+	stats, found := endpoints[addr]
+	if !found {
+		stats = &endpointStats{latencies: hdrhistogram.New(1, 90000000, 3)}
+		endpoints[addr] = stats
+	}
+	stats.commands += commands
+	if failed {
+		stats.errors += commands
+		return
+	}
+	if err := stats.latencies.RecordValue(durationMicros); err != nil {
+		log.Fatalf("Received an error while recording latencies: %v", err)
+	}
+}
+
+// doEndpointPipeline issues the pipeline to the endpoint, recording its stats.
+func doEndpointPipeline(conn *endpointClient, cmds []radix.CmdAction, commands uint64) error {
+	startT := time.Now()
+	err := conn.Do(radix.Pipeline(cmds...))
+	recordEndpointStats(conn.addr, commands, time.Since(startT).Microseconds(), err != nil)
+	return err
+}
+
+func getEndpointResults(duration time.Duration) []shardResults {
+	endpointsMutex.Lock()
+	defer endpointsMutex.Unlock()
+	addrs := make([]string, 0, len(endpoints))
+	for addr := range endpoints {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	results := []shardResults{}
+	for _, addr := range addrs {
+		stats := endpoints[addr]
+		results = append(results, newShardResults(addr, stats.commands, stats.errors, stats.latencies, duration))
+	}
+	return results
+}
+
+func printEndpointSummary(results []shardResults) {
+	if len(results) == 0 {
+		return
+	}
+	fmt.Printf("#################################################\n")
+	fmt.Printf("Summary by endpoint (latency in msec):\n")
+	fmt.Printf("    %21s %11s %11s %9s %9s %9s %9s\n", "endpoint", "commands", "ops/sec", "errors", "p50", "p95", "p99")
+	for _, result := range results {
+		fmt.Printf("    %21s %11d %11.0f %9d %9.3f %9.3f %9.3f\n", result.Node, result.Commands, result.OpsPerSec, result.Errors,
+			result.LatencyMsec["p50"], result.LatencyMsec["p95"], result.LatencyMsec["p99"])
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+const keyDistributionUniform = "uniform"
+const keyDistributionZipfian = "zipfian"
+const keyDistributionHotspot = "hotspot"
+const keyDistributionSequential = "sequential"
+const keyDistributionGaussian = "gaussian"
+
+var keyDistributions = []string{
+	keyDistributionUniform,
+	keyDistributionZipfian,
+	keyDistributionHotspot,
+	keyDistributionSequential,
+	keyDistributionGaussian,
+}
+
+type keyGeneratorConfig struct {
+	distribution string
+	keyspaceLen  uint64
+	// skew (s > 1) of the zipfian distribution. The lower key positions are the hottest ones
+	zipfSkew float64
+	// percentage of the traffic sent to the hot set, and percentage of the keyspace that is part of it
+	hotspotTrafficPercent float64
+	hotspotKeysPercent    float64
+	// standard deviation, as a percentage of the keyspace, of the gaussian distribution
+	gaussianStddevPercent float64
+	// number of keys the gaussian center moves on each request
+	gaussianCenterStep uint64
+	// first key position of the sequential scan. Each client starts on a different position
+	sequentialStart uint64
+}
+
+func (c keyGeneratorConfig) validate() error {
+	if !stringInSlice(c.distribution, keyDistributions) {
+		return fmt.Errorf("invalid key distribution %s. One of %v", c.distribution, keyDistributions)
+	}
+	if c.keyspaceLen == 0 {
+		return fmt.Errorf("keyspace length must be larger than 0")
+	}
+	if c.distribution == keyDistributionZipfian && c.zipfSkew <= 1.0 {
+		return fmt.Errorf("key zipf skew must be > 1. got %f", c.zipfSkew)
+	}
+	if c.distribution == keyDistributionHotspot {
+		if c.hotspotTrafficPercent < 0 || c.hotspotTrafficPercent > 100 {
+			return fmt.Errorf("hotspot traffic percentage must be within [0,100]. got %f", c.hotspotTrafficPercent)
+		}
+		if c.hotspotKeysPercent <= 0 || c.hotspotKeysPercent > 100 {
+			return fmt.Errorf("hotspot keys percentage must be within ]0,100]. got %f", c.hotspotKeysPercent)
+		}
+	}
+	if c.distribution == keyDistributionGaussian && c.gaussianStddevPercent <= 0 {
+		return fmt.Errorf("gaussian stddev percentage must be larger than 0. got %f", c.gaussianStddevPercent)
+	}
+	return nil
+}
+
+// keyGenerator picks the key positions accessed by a query client, as offsets within [0, keyspaceLen)
+// relative to the keyspace start.
+// Each query goroutine owns its generator, so it is not safe for concurrent use.
+type keyGenerator struct {
+	config  keyGeneratorConfig
+	r       *rand.Rand
+	zipf    *rand.Zipf
+	hotKeys uint64
+	cursor  uint64
+	center  uint64
+	stddev  float64
+}
+
+func newKeyGenerator(config keyGeneratorConfig, r *rand.Rand) *keyGenerator {
+	g := &keyGenerator{config: config, r: r}
+	switch config.distribution {
+	case keyDistributionZipfian:
+		g.zipf = rand.NewZipf(r, config.zipfSkew, 1, config.keyspaceLen-1)
+	case keyDistributionHotspot:
+		g.hotKeys = uint64(math.Ceil(float64(config.keyspaceLen) * config.hotspotKeysPercent / 100.0))
+		if g.hotKeys > config.keyspaceLen {
+			g.hotKeys = config.keyspaceLen
+		}
+	case keyDistributionSequential:
+		g.cursor = config.sequentialStart % config.keyspaceLen
+	case keyDistributionGaussian:
+		g.center = config.keyspaceLen / 2
+		g.stddev = float64(config.keyspaceLen) * config.gaussianStddevPercent / 100.0
+	}
+	return g
+}
+
+func (g *keyGenerator) next() uint64 {
+	switch g.config.distribution {
+	case keyDistributionZipfian:
+		return g.zipf.Uint64()
+	case keyDistributionHotspot:
+		if g.hotKeys == g.config.keyspaceLen || g.r.Float64()*100.0 < g.config.hotspotTrafficPercent {
+			return uint64(g.r.Int63n(int64(g.hotKeys)))
+		}
+		return g.hotKeys + uint64(g.r.Int63n(int64(g.config.keyspaceLen-g.hotKeys)))
+	case keyDistributionSequential:
+		keypos := g.cursor
+		g.cursor = (g.cursor + 1) % g.config.keyspaceLen
+		return keypos
+	case keyDistributionGaussian:
+		keyspaceLen := int64(g.config.keyspaceLen)
+		keypos := (int64(g.center) + int64(math.Round(g.r.NormFloat64()*g.stddev))) % keyspaceLen
+		if keypos < 0 {
+			keypos += keyspaceLen
+		}
+		g.center = (g.center + g.config.gaussianCenterStep) % g.config.keyspaceLen
+		return uint64(keypos)
+	default:
+		return uint64(g.r.Int63n(int64(g.config.keyspaceLen)))
+	}
+}
+
+// nextSameSlotKeyOffset returns the key offset, within [0, keyspaceLen), that follows the given one
+// while sharing its hash tag, so that the keys of a pipeline map to the same cluster slot.
+// The returned offset is relative to the keyspace start, as are the offsets of the key generator.
+func nextSameSlotKeyOffset(offset uint64, keyspaceLen uint64) uint64 {
+	step := benchKeySlotsCount()
+	offset = offset + step
+	if offset >= keyspaceLen {
+		offset = offset % step
+	}
+	return offset
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestKeyGeneratorRange(t *testing.T) {
+	tests := []struct {
+		name   string
+		config keyGeneratorConfig
+	}{
+		{"uniform", keyGeneratorConfig{distribution: keyDistributionUniform, keyspaceLen: 100}},
+		{"zipfian", keyGeneratorConfig{distribution: keyDistributionZipfian, keyspaceLen: 100, zipfSkew: 1.1}},
+		{"hotspot", keyGeneratorConfig{distribution: keyDistributionHotspot, keyspaceLen: 100, hotspotTrafficPercent: 90, hotspotKeysPercent: 10}},
+		{"hotspot all keys hot", keyGeneratorConfig{distribution: keyDistributionHotspot, keyspaceLen: 100, hotspotTrafficPercent: 50, hotspotKeysPercent: 100}},
+		{"sequential", keyGeneratorConfig{distribution: keyDistributionSequential, keyspaceLen: 100, sequentialStart: 250}},
+		{"gaussian", keyGeneratorConfig{distribution: keyDistributionGaussian, keyspaceLen: 100, gaussianStddevPercent: 200, gaussianCenterStep: 7}},
+		{"single key", keyGeneratorConfig{distribution: keyDistributionUniform, keyspaceLen: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.validate(); err != nil {
+				t.Fatalf("validate() error = %v", err)
+			}
+			g := newKeyGenerator(tt.config, rand.New(rand.NewSource(12345)))
+			for i := 0; i < 10000; i++ {
+				if keypos := g.next(); keypos >= tt.config.keyspaceLen {
+					t.Fatalf("next() = %d, out of the keyspace [0,%d)", keypos, tt.config.keyspaceLen)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyGeneratorDistributions(t *testing.T) {
+	const samples = 100000
+	tests := []struct {
+		name   string
+		config keyGeneratorConfig
+		// range of key positions, and the minimum and maximum share of the samples expected within it
+		from, to           uint64
+		minShare, maxShare float64
+	}{
+		{"uniform first half", keyGeneratorConfig{distribution: keyDistributionUniform, keyspaceLen: 1000}, 0, 500, 0.48, 0.52},
+		{"zipfian hottest key", keyGeneratorConfig{distribution: keyDistributionZipfian, keyspaceLen: 1000, zipfSkew: 2}, 0, 1, 0.55, 0.65},
+		{"hotspot hot set", keyGeneratorConfig{distribution: keyDistributionHotspot, keyspaceLen: 1000, hotspotTrafficPercent: 80, hotspotKeysPercent: 10}, 0, 100, 0.79, 0.81},
+		{"hotspot cold set", keyGeneratorConfig{distribution: keyDistributionHotspot, keyspaceLen: 1000, hotspotTrafficPercent: 80, hotspotKeysPercent: 10}, 100, 1000, 0.19, 0.21},
+		{"gaussian within one stddev", keyGeneratorConfig{distribution: keyDistributionGaussian, keyspaceLen: 1000, gaussianStddevPercent: 10}, 400, 601, 0.67, 0.70},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newKeyGenerator(tt.config, rand.New(rand.NewSource(12345)))
+			within := 0
+			for i := 0; i < samples; i++ {
+				if keypos := g.next(); keypos >= tt.from && keypos < tt.to {
+					within++
+				}
+			}
+			if share := float64(within) / samples; share < tt.minShare || share > tt.maxShare {
+				t.Errorf("share of [%d,%d) = %.3f, want within [%.2f,%.2f]", tt.from, tt.to, share, tt.minShare, tt.maxShare)
+			}
+		})
+	}
+}
+
+func TestKeyGeneratorSequential(t *testing.T) {
+	tests := []struct {
+		name            string
+		keyspaceLen     uint64
+		sequentialStart uint64
+		want            []uint64
+	}{
+		{"from the start", 4, 0, []uint64{0, 1, 2, 3, 0, 1}},
+		{"wraps around", 4, 2, []uint64{2, 3, 0, 1, 2}},
+		{"start beyond the keyspace", 4, 9, []uint64{1, 2, 3, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := keyGeneratorConfig{distribution: keyDistributionSequential, keyspaceLen: tt.keyspaceLen, sequentialStart: tt.sequentialStart}
+			g := newKeyGenerator(config, rand.New(rand.NewSource(12345)))
+			for i, want := range tt.want {
+				if got := g.next(); got != want {
+					t.Fatalf("next() #%d = %d, want %d", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyGeneratorConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  keyGeneratorConfig
+		wantErr bool
+	}{
+		{"uniform", keyGeneratorConfig{distribution: keyDistributionUniform, keyspaceLen: 10}, false},
+		{"unknown distribution", keyGeneratorConfig{distribution: "pareto", keyspaceLen: 10}, true},
+		{"empty keyspace", keyGeneratorConfig{distribution: keyDistributionUniform}, true},
+		{"zipfian skew too low", keyGeneratorConfig{distribution: keyDistributionZipfian, keyspaceLen: 10, zipfSkew: 1}, true},
+		{"hotspot traffic above 100", keyGeneratorConfig{distribution: keyDistributionHotspot, keyspaceLen: 10, hotspotTrafficPercent: 101, hotspotKeysPercent: 10}, true},
+		{"hotspot without hot keys", keyGeneratorConfig{distribution: keyDistributionHotspot, keyspaceLen: 10, hotspotTrafficPercent: 50}, true},
+		{"gaussian without stddev", keyGeneratorConfig{distribution: keyDistributionGaussian, keyspaceLen: 10}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNextSameSlotKeyOffset(t *testing.T) {
+	defer func(slots []uint16) { benchKeySlots = slots }(benchKeySlots)
+	tests := []struct {
+		name        string
+		slots       []uint16
+		offset      uint64
+		keyspaceLen uint64
+		want        uint64
+	}{
+		{"next slot cycle", nil, 5, 100000, 5 + crc16_num_slots},
+		{"wraps to the first cycle", nil, 5 + crc16_num_slots, 20000, 5},
+		{"keyspace smaller than the slots", nil, 5, 100, 5},
+		{"restricted slots", []uint16{10, 20, 30}, 1, 100, 4},
+		{"restricted slots wrap", []uint16{10, 20, 30}, 98, 100, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			benchKeySlots = tt.slots
+			got := nextSameSlotKeyOffset(tt.offset, tt.keyspaceLen)
+			if got != tt.want {
+				t.Errorf("nextSameSlotKeyOffset(%d, %d) = %d, want %d", tt.offset, tt.keyspaceLen, got, tt.want)
+			}
+			if got%benchKeySlotsCount() != tt.offset%benchKeySlotsCount() {
+				t.Errorf("nextSameSlotKeyOffset(%d, %d) = %d, which does not share the slot of the offset", tt.offset, tt.keyspaceLen, got)
+			}
+		})
+	}
+}
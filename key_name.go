@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const keyNameVarPrefix = "prefix"
+const keyNameVarTag = "tag"
+const keyNameVarNumber = "n"
+
+const defaultKeyNamePrefix = "zbench"
+
+// benchKeyTemplate is the naming scheme of the benchmark keys.
+var benchKeyTemplate = mustParseKeyNameTemplate(buildKeyNameFormat(defaultKeyNamePrefix, true), defaultKeyNamePrefix, 0)
+
+type keyNameSegment struct {
+	literal  string
+	variable string
+}
+
+// keyNameTemplate renders the benchmark key names out of a format string supporting
+// the ${prefix}, ${tag} and ${n} variables. ${tag} is the shortest string mapping to
+// the slot of the key number, and ${n} is the key number, zero padded to the template padding.
+type keyNameTemplate struct {
+	segments []keyNameSegment
+	prefix   string
+	padding  int
+}
+
+// buildKeyNameFormat returns the format of the default key naming scheme, zbench:{<slot-tag>}:<n>.
+func buildKeyNameFormat(prefix string, hashTag bool) string {
+	format := ""
+	if prefix != "" {
+		format += "${" + keyNameVarPrefix + "}:"
+	}
+	if hashTag {
+		format += "{${" + keyNameVarTag + "}}:"
+	}
+	return format + "${" + keyNameVarNumber + "}"
+}
+
+func parseKeyNameTemplate(format string, prefix string, padding int) (*keyNameTemplate, error) {
+	t := &keyNameTemplate{prefix: prefix, padding: padding}
+	hasNumber := false
+	for len(format) > 0 {
+		start := strings.Index(format, "${")
+		if start < 0 {
+			t.segments = append(t.segments, keyNameSegment{literal: format})
+			break
+		}
+		end := strings.Index(format[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated variable in key format %s", format)
+		}
+		end += start
+		variable := format[start+2 : end]
+		if variable != keyNameVarPrefix && variable != keyNameVarTag && variable != keyNameVarNumber {
+			return nil, fmt.Errorf("unknown variable ${%s} in key format. One of [${%s},${%s},${%s}]", variable, keyNameVarPrefix, keyNameVarTag, keyNameVarNumber)
+		}
+		if variable == keyNameVarNumber {
+			hasNumber = true
+		}
+		if start > 0 {
+			t.segments = append(t.segments, keyNameSegment{literal: format[:start]})
+		}
+		t.segments = append(t.segments, keyNameSegment{variable: variable})
+		format = format[end+1:]
+	}
+	if !hasNumber {
+		return nil, fmt.Errorf("the key format must contain ${%s}, otherwise all keys share the same name", keyNameVarNumber)
+	}
+	return t, nil
+}
+
+func mustParseKeyNameTemplate(format string, prefix string, padding int) *keyNameTemplate {
+	t, err := parseKeyNameTemplate(format, prefix, padding)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// hasHashTag returns true when the key names carry the slot hash tag, meaning that keys
+// sharing the same keypos modulo crc16_num_slots map to the same cluster slot.
+// Redis only hashes the content between the first { of the key name and the first } after it,
+// so ${tag} must be wrapped as {${tag}}, with no { ahead of it.
+func (t *keyNameTemplate) hasHashTag() bool {
+	// the key name ahead of ${tag}. ${n} renders digits only
+	head := ""
+	for i, segment := range t.segments {
+		switch segment.variable {
+		case keyNameVarTag:
+			closed := i+1 < len(t.segments) && strings.HasPrefix(t.segments[i+1].literal, "}")
+			return closed && strings.HasSuffix(head, "{") && strings.Count(head, "{") == 1
+		case keyNameVarPrefix:
+			head += t.prefix
+		case keyNameVarNumber:
+		default:
+			head += segment.literal
+		}
+	}
+	return false
+}
+
+func (t *keyNameTemplate) name(keypos uint64) string {
+	b := make([]byte, 0, 64)
+	for _, segment := range t.segments {
+		switch segment.variable {
+		case keyNameVarPrefix:
+			b = append(b, t.prefix...)
+		case keyNameVarTag:
+			b = append(b, crc16_slot_table[keypos%crc16_num_slots]...)
+		case keyNameVarNumber:
+			n := strconv.FormatUint(keypos, 10)
+			for i := len(n); i < t.padding; i++ {
+				b = append(b, '0')
+			}
+			b = append(b, n...)
+		default:
+			b = append(b, segment.literal...)
+		}
+	}
+	return string(b)
+}
+
+func getBenchKeyName(keypos uint64) string {
+	return benchKeyTemplate.name(benchKeyPos(keypos))
+}
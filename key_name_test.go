@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/mediocregopher/radix/v3"
+	"testing"
+)
+
+func TestParseKeyNameTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		wantErr bool
+	}{
+		{"default", buildKeyNameFormat(defaultKeyNamePrefix, true), false},
+		{"number only", "${n}", false},
+		{"literals around the number", "user:${n}:scores", false},
+		{"unterminated variable", "key:${n", true},
+		{"unknown variable", "${prefix}:${id}", true},
+		{"without number", "${prefix}:{${tag}}", true},
+		{"literal only", "key", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseKeyNameTemplate(tt.format, defaultKeyNamePrefix, 0); (err != nil) != tt.wantErr {
+				t.Errorf("parseKeyNameTemplate(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyNameTemplateName(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		prefix  string
+		padding int
+		keypos  uint64
+		want    string
+	}{
+		{"prefix and number", "${prefix}:${n}", "zbench", 0, 42, "zbench:42"},
+		{"padded number", "${prefix}:${n}", "zbench", 6, 42, "zbench:000042"},
+		{"padding shorter than the number", "${n}", "", 2, 12345, "12345"},
+		{"empty prefix", "${prefix}${n}", "", 0, 7, "7"},
+		{"hash tag", "{${tag}}:${n}", "", 0, 0, "{" + crc16_slot_table[0] + "}:0"},
+		{"hash tag of a later slot cycle", "{${tag}}:${n}", "", 0, crc16_num_slots + 3, "{" + crc16_slot_table[3] + "}:16387"},
+		{"default format", buildKeyNameFormat("zb", true), "zb", 3, 1, "zb:{" + crc16_slot_table[1] + "}:001"},
+		{"default format without hash tag", buildKeyNameFormat("zb", false), "zb", 0, 1, "zb:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := mustParseKeyNameTemplate(tt.format, tt.prefix, tt.padding)
+			if got := template.name(tt.keypos); got != tt.want {
+				t.Errorf("name(%d) = %q, want %q", tt.keypos, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyNameTemplateHasHashTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		prefix string
+		want   bool
+	}{
+		{"default format", buildKeyNameFormat(defaultKeyNamePrefix, true), defaultKeyNamePrefix, true},
+		{"tag first", "{${tag}}:${n}", "", true},
+		{"tag after the number", "${n}:{${tag}}", "", true},
+		{"tag not wrapped", "${prefix}:${tag}:${n}", "zbench", false},
+		{"tag only opened", "{${tag}:${n}", "", false},
+		{"tag wrapped along with the number", "{${tag}:${n}}", "", false},
+		{"literal ahead of the tag within the braces", "{x${tag}}:${n}", "", false},
+		{"other hash tag ahead", "{a}:{${tag}}:${n}", "", false},
+		{"brace on the prefix", "${prefix}:{${tag}}:${n}", "a{b", false},
+		{"without tag", "${prefix}:${n}", "zbench", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := mustParseKeyNameTemplate(tt.format, tt.prefix, 0)
+			if got := template.hasHashTag(); got != tt.want {
+				t.Errorf("hasHashTag() of %q = %v, want %v", tt.format, got, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+			// keys sharing the keypos modulo the slots must map to the slot of the keypos
+			for _, keypos := range []uint64{0, 1, 5000, crc16_num_slots + 5000} {
+				if slot := radix.ClusterSlot([]byte(template.name(keypos))); uint64(slot) != keypos%crc16_num_slots {
+					t.Errorf("name(%d) = %s on slot %d, want slot %d", keypos, template.name(keypos), slot, keypos%crc16_num_slots)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mediocregopher/radix/v3"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// benchKeySlots restricts the generated keys to a sorted subset of the cluster slots.
+// When nil the keys are spread over all slots.
+var benchKeySlots []uint16
+
+// benchKeyPos maps a keyspace position to the key number used on the key name.
+// Positions are mapped in order onto the key numbers whose hash tag belongs to benchKeySlots,
+// so that any contiguous range of positions only generates keys on the restricted slots.
+func benchKeyPos(keypos uint64) uint64 {
+	if benchKeySlots == nil {
+		return keypos
+	}
+	nSlots := uint64(len(benchKeySlots))
+	return (keypos/nSlots)*crc16_num_slots + uint64(benchKeySlots[keypos%nSlots])
+}
+
+// benchKeySlotsCount returns the distance between two keyspace positions mapping to the same slot.
+func benchKeySlotsCount() uint64 {
+	if benchKeySlots == nil {
+		return crc16_num_slots
+	}
+	return uint64(len(benchKeySlots))
+}
+
+// parseSlotRanges parses a comma separated list of inclusive slot ranges, e.g. 0-5460,10923-16383.
+func parseSlotRanges(ranges string) ([]uint16, error) {
+	selected := map[uint16]bool{}
+	for _, slotRange := range strings.Split(ranges, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(slotRange), "-", 2)
+		start, err := parseSlot(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = parseSlot(bounds[1]); err != nil {
+				return nil, err
+			}
+		}
+		if start > end {
+			return nil, fmt.Errorf("invalid slot range %s. The range start is larger than its end", slotRange)
+		}
+		for slot := start; slot <= end; slot++ {
+			selected[slot] = true
+		}
+	}
+	return sortedSlots(selected), nil
+}
+
+func parseSlot(slot string) (uint16, error) {
+	parsed, err := strconv.ParseUint(strings.TrimSpace(slot), 10, 16)
+	if err != nil || parsed >= crc16_num_slots {
+		return 0, fmt.Errorf("invalid slot %s. Slots are within [0,%d]", slot, crc16_num_slots-1)
+	}
+	return uint16(parsed), nil
+}
+
+// getClusterNodeSlots returns the slots served by the primary with the given address or node ID.
+func getClusterNodeSlots(cluster *radix.Cluster, node string) ([]uint16, error) {
+	for _, primary := range cluster.Topo().Primaries() {
+		if primary.Addr != node && primary.ID != node {
+			continue
+		}
+		selected := map[uint16]bool{}
+		for _, slots := range primary.Slots {
+			for slot := slots[0]; slot < slots[1]; slot++ {
+				selected[slot] = true
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("cluster node %s does not serve any slot", node)
+		}
+		return sortedSlots(selected), nil
+	}
+	return nil, fmt.Errorf("unable to find the primary %s on the cluster topology", node)
+}
+
+func sortedSlots(selected map[uint16]bool) []uint16 {
+	slots := make([]uint16, 0, len(selected))
+	for slot := range selected {
+		slots = append(slots, slot)
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+	return slots
+}
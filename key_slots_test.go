@@ -0,0 +1,71 @@
+package main
+
+import (
+	"github.com/mediocregopher/radix/v3"
+	"reflect"
+	"testing"
+)
+
+func TestParseSlotRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranges  string
+		want    []uint16
+		wantErr bool
+	}{
+		{"single slot", "7", []uint16{7}, false},
+		{"range", "3-6", []uint16{3, 4, 5, 6}, false},
+		{"ranges sorted and deduplicated", "10-12, 2,11-13", []uint16{2, 10, 11, 12, 13}, false},
+		{"last slot", "16383", []uint16{16383}, false},
+		{"slot out of range", "16384", nil, true},
+		{"range start larger than its end", "6-3", nil, true},
+		{"not a number", "a-3", nil, true},
+		{"empty range", "1,,2", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSlotRanges(tt.ranges)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSlotRanges(%q) error = %v, wantErr %v", tt.ranges, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSlotRanges(%q) = %v, want %v", tt.ranges, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBenchKeyPos(t *testing.T) {
+	defer func(slots []uint16) { benchKeySlots = slots }(benchKeySlots)
+	tests := []struct {
+		name   string
+		slots  []uint16
+		keypos uint64
+		want   uint64
+	}{
+		{"unrestricted", nil, 12345, 12345},
+		{"first restricted slot", []uint16{100, 200, 300}, 0, 100},
+		{"last restricted slot", []uint16{100, 200, 300}, 2, 300},
+		{"second cycle", []uint16{100, 200, 300}, 4, crc16_num_slots + 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			benchKeySlots = tt.slots
+			if got := benchKeyPos(tt.keypos); got != tt.want {
+				t.Errorf("benchKeyPos(%d) = %d, want %d", tt.keypos, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBenchKeyNameSlots(t *testing.T) {
+	defer func(slots []uint16) { benchKeySlots = slots }(benchKeySlots)
+	benchKeySlots = []uint16{0, 5461, 10923, 16383}
+	for keypos := uint64(0); keypos < 1000; keypos++ {
+		keyname := getBenchKeyName(keypos)
+		want := benchKeySlots[keypos%uint64(len(benchKeySlots))]
+		if slot := radix.ClusterSlot([]byte(keyname)); slot != want {
+			t.Fatalf("getBenchKeyName(%d) = %s on slot %d, want slot %d", keypos, keyname, slot, want)
+		}
+	}
+}
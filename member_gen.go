@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+const memberSizeFixed = "fixed"
+const memberSizeUniform = "uniform"
+const memberSizeZipfian = "zipfian"
+
+var memberSizeDistributions = []string{memberSizeFixed, memberSizeUniform, memberSizeZipfian}
+
+const memberCharsetLower = "lower"
+const memberCharsetAlnum = "alnum"
+const memberCharsetHex = "hex"
+const memberCharsetBinary = "binary"
+
+var memberCharsets = map[string]string{
+	memberCharsetLower:  charset,
+	memberCharsetAlnum:  "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+	memberCharsetHex:    "0123456789abcdef",
+	memberCharsetBinary: "",
+}
+
+const memberFormatRandom = "random"
+const memberFormatPrefixedId = "prefixed-id"
+const memberFormatUUID = "uuid"
+
+var memberFormats = []string{memberFormatRandom, memberFormatPrefixedId, memberFormatUUID}
+
+const digitsCharset = "0123456789"
+
+type memberGeneratorConfig struct {
+	sizeDistribution string
+	// member size used by the fixed distribution
+	size uint64
+	// member size range used by the uniform and zipfian distributions
+	sizeMin  uint64
+	sizeMax  uint64
+	zipfSkew float64
+	charset  string
+	format   string
+	prefix   string
+	// size in bytes of the pre-generated random data each client slices random members from.
+	// 0 generates every member byte by byte.
+	poolSize uint64
+}
+
+func (c memberGeneratorConfig) validate() error {
+	if !stringInSlice(c.sizeDistribution, memberSizeDistributions) {
+		return fmt.Errorf("invalid member size distribution %s. One of %v", c.sizeDistribution, memberSizeDistributions)
+	}
+	if _, found := memberCharsets[c.charset]; !found {
+		return fmt.Errorf("invalid member charset %s. One of [%s]", c.charset, memberCharsetNames())
+	}
+	if !stringInSlice(c.format, memberFormats) {
+		return fmt.Errorf("invalid member format %s. One of %v", c.format, memberFormats)
+	}
+	if c.sizeDistribution != memberSizeFixed && c.sizeMin > c.sizeMax {
+		return fmt.Errorf("member size min (%d) is larger than member size max (%d)", c.sizeMin, c.sizeMax)
+	}
+	if c.sizeDistribution == memberSizeZipfian && c.zipfSkew <= 1.0 {
+		return fmt.Errorf("member size zipf skew must be > 1. got %f", c.zipfSkew)
+	}
+	return nil
+}
+
+func stringInSlice(s string, list []string) bool {
+	for _, elem := range list {
+		if elem == s {
+			return true
+		}
+	}
+	return false
+}
+
+// memberGenerator produces the members for the loaded sorted sets.
+// Each load goroutine owns its generator, so it is not safe for concurrent use.
+type memberGenerator struct {
+	config memberGeneratorConfig
+	r      *rand.Rand
+	zipf   *rand.Zipf
+	// pre-generated random data random members are sliced from, when enabled
+	pool []byte
+}
+
+func newMemberGenerator(config memberGeneratorConfig, r *rand.Rand) *memberGenerator {
+	g := &memberGenerator{config: config, r: r}
+	if config.sizeDistribution == memberSizeZipfian {
+		g.zipf = rand.NewZipf(r, config.zipfSkew, 1, config.sizeMax-config.sizeMin)
+	}
+	if config.poolSize > 0 && config.format == memberFormatRandom {
+		g.pool = g.appendRandom(make([]byte, 0, config.poolSize), config.poolSize)
+	}
+	return g
+}
+
+// nextSize returns the size of the next member, following the configured size distribution.
+func (g *memberGenerator) nextSize() uint64 {
+	switch g.config.sizeDistribution {
+	case memberSizeUniform:
+		return g.config.sizeMin + uint64(g.r.Int63n(int64(g.config.sizeMax-g.config.sizeMin+1)))
+	case memberSizeZipfian:
+		return g.config.sizeMin + g.zipf.Uint64()
+	default:
+		return g.config.size
+	}
+}
+
+// appendNext appends a member with the given size to dst, and returns the extended buffer.
+// UUID members have a fixed size and ignore it.
+func (g *memberGenerator) appendNext(dst []byte, size uint64) []byte {
+	switch g.config.format {
+	case memberFormatUUID:
+		return g.appendUUID(dst)
+	case memberFormatPrefixedId:
+		idLen := 1
+		if int(size) > len(g.config.prefix) {
+			idLen = int(size) - len(g.config.prefix)
+		}
+		dst = append(dst, g.config.prefix...)
+		return appendWithCharset(dst, idLen, digitsCharset, g.r)
+	default:
+		return g.appendRandom(dst, size)
+	}
+}
+
+func (g *memberGenerator) appendRandom(dst []byte, size uint64) []byte {
+	if g.pool != nil && size <= uint64(len(g.pool)) {
+		offset := g.r.Intn(len(g.pool) - int(size) + 1)
+		return append(dst, g.pool[offset:offset+int(size)]...)
+	}
+	if g.config.charset == memberCharsetBinary {
+		dst, b := growBytes(dst, int(size))
+		g.r.Read(b)
+		return dst
+	}
+	return appendWithCharset(dst, int(size), memberCharsets[g.config.charset], g.r)
+}
+
+// appendUUID appends a random (version 4) UUID built from the generator's own random source,
+// so that datasets are reproducible given the same seed.
+func (g *memberGenerator) appendUUID(dst []byte) []byte {
+	var b [16]byte
+	g.r.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	for i, part := range [][]byte{b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]} {
+		if i > 0 {
+			dst = append(dst, '-')
+		}
+		var encoded []byte
+		dst, encoded = growBytes(dst, hex.EncodedLen(len(part)))
+		hex.Encode(encoded, part)
+	}
+	return dst
+}
+
+// growBytes extends dst by n bytes, and returns the extended buffer along with the added bytes.
+func growBytes(dst []byte, n int) ([]byte, []byte) {
+	l := len(dst)
+	if cap(dst)-l < n {
+		grown := make([]byte, l, 2*cap(dst)+n)
+		copy(grown, dst)
+		dst = grown
+	}
+	dst = dst[:l+n]
+	return dst, dst[l:]
+}
+
+func appendWithCharset(dst []byte, length int, charset string, r *rand.Rand) []byte {
+	for i := 0; i < length; i++ {
+		dst = append(dst, charset[r.Intn(len(charset))])
+	}
+	return dst
+}
+
+func memberCharsetNames() string {
+	return strings.Join([]string{memberCharsetLower, memberCharsetAlnum, memberCharsetHex, memberCharsetBinary}, ",")
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMemberGeneratorNextSize(t *testing.T) {
+	tests := []struct {
+		name             string
+		config           memberGeneratorConfig
+		minSize, maxSize uint64
+	}{
+		{"fixed", memberGeneratorConfig{sizeDistribution: memberSizeFixed, size: 10}, 10, 10},
+		{"uniform", memberGeneratorConfig{sizeDistribution: memberSizeUniform, sizeMin: 5, sizeMax: 20}, 5, 20},
+		{"uniform single size", memberGeneratorConfig{sizeDistribution: memberSizeUniform, sizeMin: 7, sizeMax: 7}, 7, 7},
+		{"zipfian", memberGeneratorConfig{sizeDistribution: memberSizeZipfian, sizeMin: 5, sizeMax: 500, zipfSkew: 1.5}, 5, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newMemberGenerator(tt.config, rand.New(rand.NewSource(12345)))
+			seen := map[uint64]bool{}
+			for i := 0; i < 10000; i++ {
+				size := g.nextSize()
+				if size < tt.minSize || size > tt.maxSize {
+					t.Fatalf("nextSize() = %d, out of [%d,%d]", size, tt.minSize, tt.maxSize)
+				}
+				seen[size] = true
+			}
+			// the zipfian sizes rarely reach the max size
+			if !seen[tt.minSize] {
+				t.Errorf("nextSize() did not generate the min size %d", tt.minSize)
+			}
+			if tt.config.sizeDistribution != memberSizeZipfian && !seen[tt.maxSize] {
+				t.Errorf("nextSize() did not generate the max size %d", tt.maxSize)
+			}
+		})
+	}
+}
+
+func TestMemberGeneratorAppendNext(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	tests := []struct {
+		name   string
+		config memberGeneratorConfig
+		size   uint64
+		check  func(member string) bool
+	}{
+		{"random lower", memberGeneratorConfig{format: memberFormatRandom, charset: memberCharsetLower}, 12, func(member string) bool {
+			return len(member) == 12 && strings.Trim(member, memberCharsets[memberCharsetLower]) == ""
+		}},
+		{"random hex", memberGeneratorConfig{format: memberFormatRandom, charset: memberCharsetHex}, 8, func(member string) bool {
+			return len(member) == 8 && strings.Trim(member, memberCharsets[memberCharsetHex]) == ""
+		}},
+		{"random binary", memberGeneratorConfig{format: memberFormatRandom, charset: memberCharsetBinary}, 16, func(member string) bool {
+			return len(member) == 16
+		}},
+		{"random from the pool", memberGeneratorConfig{format: memberFormatRandom, charset: memberCharsetAlnum, poolSize: 64}, 10, func(member string) bool {
+			return len(member) == 10 && strings.Trim(member, memberCharsets[memberCharsetAlnum]) == ""
+		}},
+		{"random larger than the pool", memberGeneratorConfig{format: memberFormatRandom, charset: memberCharsetAlnum, poolSize: 4}, 10, func(member string) bool {
+			return len(member) == 10 && strings.Trim(member, memberCharsets[memberCharsetAlnum]) == ""
+		}},
+		{"prefixed id", memberGeneratorConfig{format: memberFormatPrefixedId, prefix: "user:"}, 12, func(member string) bool {
+			return len(member) == 12 && strings.HasPrefix(member, "user:") && strings.Trim(member[5:], digitsCharset) == ""
+		}},
+		{"prefixed id shorter than the prefix", memberGeneratorConfig{format: memberFormatPrefixedId, prefix: "user:"}, 3, func(member string) bool {
+			return len(member) == 6 && strings.HasPrefix(member, "user:")
+		}},
+		{"uuid ignores the size", memberGeneratorConfig{format: memberFormatUUID}, 5, func(member string) bool {
+			return uuidPattern.MatchString(member)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newMemberGenerator(tt.config, rand.New(rand.NewSource(12345)))
+			dst := []byte("head")
+			for i := 0; i < 100; i++ {
+				dst = g.appendNext(dst[:4], tt.size)
+				if string(dst[:4]) != "head" {
+					t.Fatalf("appendNext() modified the buffer head: %q", dst)
+				}
+				if member := string(dst[4:]); !tt.check(member) {
+					t.Fatalf("unexpected member %q", member)
+				}
+			}
+		})
+	}
+}
+
+func TestMemberGeneratorReproducible(t *testing.T) {
+	config := memberGeneratorConfig{sizeDistribution: memberSizeUniform, sizeMin: 1, sizeMax: 30, format: memberFormatRandom, charset: memberCharsetAlnum}
+	g1 := newMemberGenerator(config, rand.New(rand.NewSource(7)))
+	g2 := newMemberGenerator(config, rand.New(rand.NewSource(7)))
+	for i := 0; i < 100; i++ {
+		m1, m2 := string(g1.appendNext(nil, g1.nextSize())), string(g2.appendNext(nil, g2.nextSize()))
+		if m1 != m2 {
+			t.Fatalf("member #%d differs across generators with the same seed: %q and %q", i, m1, m2)
+		}
+	}
+}
+
+func TestMemberGeneratorConfigValidate(t *testing.T) {
+	valid := memberGeneratorConfig{sizeDistribution: memberSizeFixed, size: 10, charset: memberCharsetLower, format: memberFormatRandom}
+	tests := []struct {
+		name    string
+		update  func(c *memberGeneratorConfig)
+		wantErr bool
+	}{
+		{"valid", func(c *memberGeneratorConfig) {}, false},
+		{"unknown size distribution", func(c *memberGeneratorConfig) { c.sizeDistribution = "normal" }, true},
+		{"unknown charset", func(c *memberGeneratorConfig) { c.charset = "utf8" }, true},
+		{"unknown format", func(c *memberGeneratorConfig) { c.format = "ulid" }, true},
+		{"size min larger than max", func(c *memberGeneratorConfig) { c.sizeDistribution, c.sizeMin, c.sizeMax = memberSizeUniform, 10, 5 }, true},
+		{"zipfian skew too low", func(c *memberGeneratorConfig) { c.sizeDistribution, c.sizeMax, c.zipfSkew = memberSizeZipfian, 10, 1 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.update(&config)
+			if err := config.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mediocregopher/radix/v3"
+	"log"
+	"math/rand"
+	"strconv"
+)
+
+// size of the double score stored along with each member, accounted on the raw payload
+const scorePayloadSize = 8
+
+// memoryState is the used_memory and the number of keys of the benchmark database, summed across the primaries.
+type memoryState struct {
+	usedMemory int64
+	keys       int64
+}
+
+// getMemoryState returns the memory state summed across the primaries on cluster connections,
+// and across the given addresses otherwise.
+func getMemoryState(conn radix.Client, addrs []string, clients []radix.Client, db int) memoryState {
+	state := memoryState{}
+	addrs, clients = getNodeClients(conn, addrs, clients, true)
+	for i, client := range clients {
+		info, err := getInfo(client, "memory", "keyspace")
+		if err != nil {
+			log.Printf("Received an error while issuing INFO memory and keyspace on %s: %v", addrs[i], err)
+			continue
+		}
+		nodeMemory, _ := strconv.ParseInt(info["used_memory"], 10, 64)
+		nodeKeys, _ := strconv.ParseInt(parseInfoFields(info[fmt.Sprintf("db%d", db)])["keys"], 10, 64)
+		state.usedMemory += nodeMemory
+		state.keys += nodeKeys
+	}
+	return state
+}
+
+// memoryFootprintResults is the memory footprint of the loaded dataset. The used_memory deltas
+// account for the whole load, while the MEMORY USAGE figures are out of the sampled keys.
+type memoryFootprintResults struct {
+	UsedMemoryBefore int64
+	UsedMemoryAfter  int64
+	UsedMemoryDelta  int64
+	KeysBefore       int64
+	KeysAfter        int64
+	// keys created by the load, out of the INFO keyspace delta
+	LoadedKeys int64
+	// members and generated member bytes of the successful ZADD commands
+	LoadedMembers     uint64
+	LoadedMemberBytes uint64
+	// unset when the keyspace was not empty before the load, given that the deltas then also account for overwritten keys
+	UsedMemoryPerKey      float64
+	UsedMemoryPerMember   float64
+	Warning               string `json:",omitempty"`
+	SampledKeys           uint64
+	MissingKeys           uint64
+	MemoryUsageSamples    int
+	SampledMembers        uint64
+	MemoryUsagePerKey     float64
+	MemoryUsagePerMember  float64
+	RawPayloadPerMember   float64
+	RawPayloadPerKey      float64
+	OverheadPerKey        float64
+	OverheadPerMember     float64
+	MemoryUsageToRawRatio float64
+}
+
+// getMemoryFootprint computes the footprint of the load out of the memory state before and after it, and samples
+// MEMORY USAGE, with usageSamples nested values, and ZCARD on random keys of the keyspace.
+// The raw payload of each member is its average generated size plus its double score.
+func getMemoryFootprint(conn radix.Client, before, after memoryState, loadedMembers, loadedMemberBytes uint64, keyspaceStart, keyspaceLen, samples uint64, usageSamples int, seed int64) *memoryFootprintResults {
+	results := &memoryFootprintResults{
+		UsedMemoryBefore:   before.usedMemory,
+		UsedMemoryAfter:    after.usedMemory,
+		UsedMemoryDelta:    after.usedMemory - before.usedMemory,
+		KeysBefore:         before.keys,
+		KeysAfter:          after.keys,
+		LoadedKeys:         after.keys - before.keys,
+		LoadedMembers:      loadedMembers,
+		LoadedMemberBytes:  loadedMemberBytes,
+		MemoryUsageSamples: usageSamples,
+	}
+	if loadedMembers > 0 {
+		results.RawPayloadPerMember = float64(loadedMemberBytes)/float64(loadedMembers) + scorePayloadSize
+	}
+	if before.keys > 0 {
+		results.Warning = fmt.Sprintf("the keyspace had %d keys before the load, so the used_memory delta per key and per member is not reported", before.keys)
+	} else if results.LoadedKeys > 0 && loadedMembers > 0 {
+		results.UsedMemoryPerKey = float64(results.UsedMemoryDelta) / float64(results.LoadedKeys)
+		results.UsedMemoryPerMember = float64(results.UsedMemoryDelta) / float64(loadedMembers)
+	}
+	r := rand.New(rand.NewSource(seed))
+	var memoryUsage int64 = 0
+	for i := uint64(0); i < samples && keyspaceLen > 0; i++ {
+		keyname := getBenchKeyName(keyspaceStart + uint64(r.Int63n(int64(keyspaceLen))))
+		client, err := getClientForKey(conn, keyname)
+		if err != nil {
+			log.Fatalf("Received an error while resolving the node for key %s: %v", keyname, err)
+		}
+		var usage int64
+		mn := radix.MaybeNil{Rcv: &usage}
+		err = client.Do(radix.Cmd(&mn, "MEMORY", "USAGE", keyname, "SAMPLES", strconv.Itoa(usageSamples)))
+		if err != nil {
+			// e.g. MEMORY not allowed by the ACL user, in which case only the used_memory deltas are reported
+			log.Printf("Unable to sample the memory usage, received an error while issuing MEMORY USAGE %s: %v", keyname, err)
+			break
+		}
+		if mn.Nil {
+			results.MissingKeys++
+			continue
+		}
+		var zcard uint64
+		err = client.Do(radix.Cmd(&zcard, "ZCARD", keyname))
+		if err != nil {
+			log.Printf("Unable to sample the memory usage, received an error while issuing ZCARD %s: %v", keyname, err)
+			break
+		}
+		results.SampledKeys++
+		results.SampledMembers += zcard
+		memoryUsage += usage
+	}
+	if results.SampledKeys > 0 {
+		results.MemoryUsagePerKey = float64(memoryUsage) / float64(results.SampledKeys)
+		results.RawPayloadPerKey = float64(results.SampledMembers) * results.RawPayloadPerMember / float64(results.SampledKeys)
+		results.OverheadPerKey = results.MemoryUsagePerKey - results.RawPayloadPerKey
+	}
+	if results.SampledMembers > 0 {
+		results.MemoryUsagePerMember = float64(memoryUsage) / float64(results.SampledMembers)
+		results.OverheadPerMember = results.MemoryUsagePerMember - results.RawPayloadPerMember
+		results.MemoryUsageToRawRatio = results.MemoryUsagePerMember / results.RawPayloadPerMember
+	}
+	return results
+}
+
+func printMemoryFootprintSummary(results *memoryFootprintResults) {
+	fmt.Printf("#################################################\n")
+	fmt.Printf("Memory footprint of the load\n")
+	fmt.Printf("used_memory before %d. After %d. Delta %d bytes\n", results.UsedMemoryBefore, results.UsedMemoryAfter, results.UsedMemoryDelta)
+	fmt.Printf("Keys before %d. After %d. Loaded keys %d. Loaded members %d (%d member bytes)\n", results.KeysBefore, results.KeysAfter, results.LoadedKeys, results.LoadedMembers, results.LoadedMemberBytes)
+	if results.Warning != "" {
+		fmt.Printf("WARNING: %s\n", results.Warning)
+	} else {
+		fmt.Printf("used_memory delta per key %.1f bytes. Per member %.1f bytes\n", results.UsedMemoryPerKey, results.UsedMemoryPerMember)
+	}
+	fmt.Printf("MEMORY USAGE on %d sampled keys (SAMPLES %d). Missing keys %d. Sampled members %d\n", results.SampledKeys, results.MemoryUsageSamples, results.MissingKeys, results.SampledMembers)
+	fmt.Printf("    %10s %13s %13s %13s %9s\n", "", "usage bytes", "raw bytes", "overhead", "ratio")
+	fmt.Printf("    %10s %13.1f %13.1f %13.1f %9.2f\n", "per key", results.MemoryUsagePerKey, results.RawPayloadPerKey, results.OverheadPerKey, results.MemoryUsageToRawRatio)
+	fmt.Printf("    %10s %13.1f %13.1f %13.1f %9.2f\n", "per member", results.MemoryUsagePerMember, results.RawPayloadPerMember, results.OverheadPerMember, results.MemoryUsageToRawRatio)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/mediocregopher/radix/v3"
+	"github.com/mediocregopher/radix/v3/resp/resp2"
+	"io"
+	"sync"
+	"time"
+)
+
+// queuedCmd wraps a command issued within MULTI/EXEC. Its +QUEUED reply is discarded,
+// while its actual reply is decoded by the wrapped command when reading the EXEC reply.
+type queuedCmd struct {
+	radix.CmdAction
+}
+
+func (c queuedCmd) UnmarshalRESP(br *bufio.Reader) error {
+	return resp2.Any{}.UnmarshalRESP(br)
+}
+
+func (c queuedCmd) Run(conn radix.Conn) error {
+	if err := conn.Encode(c); err != nil {
+		return err
+	}
+	return conn.Decode(c)
+}
+
+// execCmd is an EXEC command whose reply elements are decoded by the queued commands,
+// in order, so that each command receives its own reply as if it was issued outside MULTI/EXEC.
+type execCmd struct {
+	cmds []radix.CmdAction
+}
+
+func (c execCmd) Keys() []string {
+	return nil
+}
+
+func (c execCmd) Run(conn radix.Conn) error {
+	if err := conn.Encode(c); err != nil {
+		return err
+	}
+	return conn.Decode(c)
+}
+
+func (c execCmd) MarshalRESP(w io.Writer) error {
+	_, err := io.WriteString(w, "*1\r\n$4\r\nEXEC\r\n")
+	return err
+}
+
+func (c execCmd) UnmarshalRESP(br *bufio.Reader) error {
+	var header resp2.ArrayHeader
+	if err := header.UnmarshalRESP(br); err != nil {
+		return err
+	}
+	if header.N < 0 {
+		return errors.New("transaction aborted (EXEC returned nil)")
+	}
+	var firstErr error
+	for i := 0; i < header.N; i++ {
+		var err error
+		if i < len(c.cmds) {
+			err = c.cmds[i].UnmarshalRESP(br)
+		} else {
+			err = resp2.Any{}.UnmarshalRESP(br)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// withMulti wraps the commands in MULTI/EXEC.
+func withMulti(cmds []radix.CmdAction) []radix.CmdAction {
+	wrapped := make([]radix.CmdAction, 0, len(cmds)+2)
+	wrapped = append(wrapped, radix.Cmd(nil, "MULTI"))
+	for _, cmd := range cmds {
+		wrapped = append(wrapped, queuedCmd{cmd})
+	}
+	return append(wrapped, execCmd{cmds: cmds})
+}
+
+// slotGroup holds the commands of a pipeline that map to a single slot.
+type slotGroup struct {
+	slot     uint16
+	cmds     []radix.CmdAction
+	catchers []*redirectCatcher
+	// set when any of the group commands was redirected
+	redirected bool
+}
+
+// nodeBatch holds the commands of a pipeline that are issued to a single cluster node.
+type nodeBatch struct {
+	addr   string
+	groups []*slotGroup
+	// number of benchmark commands per slot range of the node
+	slotRangeCommands map[[2]uint16]uint64
+}
+
+// wireCmds returns the commands sent on the wire, including the MULTI/EXEC wrappers,
+// each wrapped so that MOVED and ASK redirects are caught.
+func (b *nodeBatch) wireCmds(multi bool) []radix.CmdAction {
+	wire := []radix.CmdAction{}
+	for _, group := range b.groups {
+		group.redirected = false
+		group.catchers = group.catchers[:0]
+		cmds := group.cmds
+		if multi {
+			cmds = withMulti(cmds)
+		}
+		for _, cmd := range cmds {
+			catcher := &redirectCatcher{CmdAction: cmd, group: group}
+			if _, isQueued := cmd.(queuedCmd); isQueued || !multi {
+				group.catchers = append(group.catchers, catcher)
+			}
+			wire = append(wire, catcher)
+		}
+	}
+	return wire
+}
+
+// doBenchPipeline issues the commands as a single pipeline, wrapped in MULTI/EXEC when multi is set,
+// and returns the number of commands that failed along with the first error.
+// On cluster connections the commands are grouped per node, and for multi per slot within each node,
+// given that a pipeline can only be issued to one node and a transaction can only span one slot.
+// The node batches are issued concurrently, and their stats recorded per shard.
+// On sentinel connections the outcome of each pipeline tracks the primary unavailability windows,
+// and on -hosts endpoint connections the stats are recorded per endpoint.
+func doBenchPipeline(conn radix.Client, cmds []radix.CmdAction, multi bool) (uint64, error) {
+	cluster, isCluster := conn.(*radix.Cluster)
+	if !isCluster {
+		commands := uint64(len(cmds))
+		if multi {
+			cmds = withMulti(cmds)
+		}
+		var failed uint64 = 0
+		var err error
+		if endpoint, isEndpoint := conn.(*endpointClient); isEndpoint {
+			err = doEndpointPipeline(endpoint, cmds, commands)
+		} else {
+			err = conn.Do(radix.Pipeline(cmds...))
+		}
+		if err != nil {
+			failed = commands
+		}
+		if _, isSentinel := conn.(*radix.Sentinel); isSentinel {
+			recordAvailability(failed, err)
+		}
+		return failed, err
+	}
+	return doClusterPipeline(cluster, cmds, multi, clusterRedirectAttempts)
+}
+
+func doClusterPipeline(cluster *radix.Cluster, cmds []radix.CmdAction, multi bool, attempts int) (uint64, error) {
+	if attempts <= 0 {
+		return uint64(len(cmds)), fmt.Errorf("commands still redirected after %d attempts", clusterRedirectAttempts)
+	}
+	batches, err := groupPerNode(cluster, cmds)
+	if err != nil {
+		return uint64(len(cmds)), err
+	}
+	if len(batches) == 1 {
+		return doNodeBatch(cluster, batches[0], multi, attempts)
+	}
+	var wg sync.WaitGroup
+	failures := make([]uint64, len(batches))
+	errs := make([]error, len(batches))
+	for i := range batches {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			failures[i], errs[i] = doNodeBatch(cluster, batches[i], multi, attempts)
+		}(i)
+	}
+	wg.Wait()
+	var failed uint64 = 0
+	var firstErr error
+	for i := range batches {
+		failed += failures[i]
+		if errs[i] != nil && firstErr == nil {
+			firstErr = errs[i]
+		}
+	}
+	return failed, firstErr
+}
+
+// groupPerNode groups the commands per node serving their slot, which is the slot primary
+// or, with readFromReplica, one of its replicas.
+func groupPerNode(cluster *radix.Cluster, cmds []radix.CmdAction) ([]*nodeBatch, error) {
+	topo := cluster.Topo()
+	primaries := topo.Primaries()
+	var replicas map[string][]string
+	var pick uint64
+	if readFromReplica {
+		replicas = getClusterReplicas(topo)
+		pick = nextReplicaPick()
+	}
+	batches := []*nodeBatch{}
+	nodeBatches := map[string]*nodeBatch{}
+	slotGroups := map[uint16]*slotGroup{}
+	for _, cmd := range cmds {
+		keys := cmd.Keys()
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("unable to route command %v without keys on cluster mode", cmd)
+		}
+		slot := radix.ClusterSlot([]byte(keys[0]))
+		addr, slotRange := getPrimaryForSlot(primaries, slot)
+		if addr == "" {
+			return nil, fmt.Errorf("unable to find the primary serving slot %d", slot)
+		}
+		if readFromReplica {
+			addr = pickReplica(replicas, addr, pick)
+		}
+		batch, found := nodeBatches[addr]
+		if !found {
+			batch = &nodeBatch{addr: addr, slotRangeCommands: map[[2]uint16]uint64{}}
+			nodeBatches[addr] = batch
+			batches = append(batches, batch)
+		}
+		group, found := slotGroups[slot]
+		if !found {
+			group = &slotGroup{slot: slot}
+			slotGroups[slot] = group
+			batch.groups = append(batch.groups, group)
+		}
+		group.cmds = append(group.cmds, cmd)
+		batch.slotRangeCommands[slotRange]++
+	}
+	return batches, nil
+}
+
+func doNodeBatch(cluster *radix.Cluster, batch *nodeBatch, multi bool, attempts int) (uint64, error) {
+	var commands uint64 = 0
+	for _, count := range batch.slotRangeCommands {
+		commands += count
+	}
+	client, err := cluster.Client(batch.addr)
+	if err != nil {
+		recordShardStats(batch, 0, true)
+		return commands, fmt.Errorf("unable to get a client for node %s: %v", batch.addr, err)
+	}
+	wire := batch.wireCmds(multi)
+	startT := time.Now()
+	err = client.Do(radix.Pipeline(wire...))
+	endT := time.Now()
+	recordShardStats(batch, endT.Sub(startT).Microseconds(), err != nil)
+	if err != nil {
+		return commands, err
+	}
+	return retryRedirected(cluster, batch.groups, multi, attempts)
+}
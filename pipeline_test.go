@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/mediocregopher/radix/v3"
+	"github.com/mediocregopher/radix/v3/resp"
+	"github.com/mediocregopher/radix/v3/resp/resp2"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithMulti(t *testing.T) {
+	cmds := []radix.CmdAction{
+		radix.Cmd(nil, "ZRANGE", "k1", "0", "1", "BYSCORE"),
+		radix.Cmd(nil, "ZRANGE", "k2", "0", "1", "BYSCORE"),
+	}
+	wrapped := withMulti(cmds)
+	if len(wrapped) != len(cmds)+2 {
+		t.Fatalf("len(withMulti()) = %d, want %d", len(wrapped), len(cmds)+2)
+	}
+	if keys := wrapped[0].Keys(); len(keys) != 0 {
+		t.Errorf("first command keys = %v, want the MULTI command", keys)
+	}
+	for i, cmd := range cmds {
+		queued, isQueued := wrapped[i+1].(queuedCmd)
+		if !isQueued || queued.CmdAction != cmd {
+			t.Errorf("command #%d = %v, want the queued command %v", i+1, wrapped[i+1], cmd)
+		}
+	}
+	exec, isExec := wrapped[len(wrapped)-1].(execCmd)
+	if !isExec || len(exec.cmds) != len(cmds) {
+		t.Errorf("last command = %v, want EXEC decoding the %d commands", wrapped[len(wrapped)-1], len(cmds))
+	}
+}
+
+func TestExecCmdUnmarshalRESP(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    []string
+		wantErr string
+	}{
+		{"replies decoded in order", "*2\r\n$1\r\na\r\n$1\r\nb\r\n", []string{"a", "b"}, ""},
+		{"aborted transaction", "*-1\r\n", []string{"", ""}, "transaction aborted"},
+		{"first command error", "*2\r\n-WRONGTYPE wrong kind of value\r\n$1\r\nb\r\n", []string{"", "b"}, "WRONGTYPE"},
+		{"extra replies discarded", "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n", []string{"a", "b"}, ""},
+		{"EXECABORT", "-EXECABORT Transaction discarded because of previous errors.\r\n", []string{"", ""}, "EXECABORT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 2)
+			exec := execCmd{cmds: []radix.CmdAction{radix.Cmd(&got[0], "GET", "a"), radix.Cmd(&got[1], "GET", "b")}}
+			err := exec.UnmarshalRESP(bufio.NewReader(strings.NewReader(tt.reply)))
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("UnmarshalRESP() error = %v, want %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalRESP() decoded %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newPipelineStub returns a connection replying to MULTI/EXEC as the server does, failing the given command.
+func newPipelineStub(failing string) radix.Conn {
+	inMulti := false
+	return radix.Stub("tcp", "127.0.0.1:6379", func(args []string) interface{} {
+		command := strings.ToUpper(args[0])
+		if command == failing {
+			return errors.New("ERR failing " + command)
+		}
+		switch {
+		case command == "MULTI":
+			inMulti = true
+			return "OK"
+		case command == "EXEC":
+			inMulti = false
+			return []interface{}{[]string{}, []string{}, []string{}}
+		case inMulti:
+			return "QUEUED"
+		}
+		return []string{}
+	})
+}
+
+func TestDoBenchPipelineFailedCommands(t *testing.T) {
+	tests := []struct {
+		name       string
+		multi      bool
+		failing    string
+		wantFailed uint64
+	}{
+		{"pipeline", false, "", 0},
+		{"failed pipeline", false, "ZRANGE", 3},
+		{"transaction", true, "", 0},
+		{"failed EXEC", true, "EXEC", 3},
+		{"failed MULTI", true, "MULTI", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds := []radix.CmdAction{}
+			for _, key := range []string{"k1", "k2", "k3"} {
+				cmds = append(cmds, radix.Cmd(nil, "ZRANGE", key, "0", "1", "BYSCORE"))
+			}
+			failed, err := doBenchPipeline(newPipelineStub(tt.failing), cmds, tt.multi)
+			if (err != nil) != (tt.failing != "") {
+				t.Errorf("doBenchPipeline() error = %v, failing %q", err, tt.failing)
+			}
+			// the MULTI/EXEC wrappers are not accounted as failed benchmark commands
+			if failed != tt.wantFailed {
+				t.Errorf("doBenchPipeline() failed = %d, want %d", failed, tt.wantFailed)
+			}
+		})
+	}
+}
+
+// stubNodeConn is a cluster node connection replying to each command with the reply of fn.
+// Contrary to radix.Stub, error replies do not end the pipeline, as on a MOVED within a transaction.
+type stubNodeConn struct {
+	radix.Conn
+	addr    string
+	fn      func(addr string, args []string) interface{}
+	mutex   sync.Mutex
+	replies bytes.Buffer
+	br      *bufio.Reader
+}
+
+func newStubNodeConn(addr string, fn func(addr string, args []string) interface{}) *stubNodeConn {
+	c := &stubNodeConn{addr: addr, fn: fn}
+	c.br = bufio.NewReader(&c.replies)
+	return c
+}
+
+func (c *stubNodeConn) Do(a radix.Action) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return a.Run(c)
+}
+
+func (c *stubNodeConn) Encode(m resp.Marshaler) error {
+	var buf bytes.Buffer
+	if err := m.MarshalRESP(&buf); err != nil {
+		return err
+	}
+	br := bufio.NewReader(&buf)
+	for buf.Len() > 0 || br.Buffered() > 0 {
+		var args []string
+		if err := (resp2.Any{I: &args}).UnmarshalRESP(br); err != nil {
+			return err
+		}
+		if err := (resp2.Any{I: c.fn(c.addr, args)}).MarshalRESP(&c.replies); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *stubNodeConn) Decode(u resp.Unmarshaler) error {
+	return u.UnmarshalRESP(c.br)
+}
+
+func (c *stubNodeConn) Close() error {
+	return nil
+}
+
+// stubClusterSlots is the CLUSTER SLOTS reply of two primaries, 127.0.0.1:7000 serving the slots
+// up to lastSlotA and 127.0.0.1:7001 serving the remaining ones.
+func stubClusterSlots(lastSlotA int) []interface{} {
+	return []interface{}{
+		[]interface{}{0, lastSlotA, []interface{}{"127.0.0.1", 7000, "node-a"}},
+		[]interface{}{lastSlotA + 1, 16383, []interface{}{"127.0.0.1", 7001, "node-b"}},
+	}
+}
+
+// newStubCluster returns a cluster of two primaries, serving the slots [0,8191] and [8192,16383]
+// unless slots is set. The nodes reply to the other commands with reply, or with an empty array when nil.
+func newStubCluster(t *testing.T, slots func() []interface{}, reply func(addr string, args []string) interface{}) *radix.Cluster {
+	if slots == nil {
+		slots = func() []interface{} { return stubClusterSlots(8191) }
+	}
+	poolFunc := func(network, addr string) (radix.Client, error) {
+		return newStubNodeConn(addr, func(addr string, args []string) interface{} {
+			if len(args) == 2 && strings.ToUpper(args[0]) == "CLUSTER" && strings.ToUpper(args[1]) == "SLOTS" {
+				return slots()
+			}
+			if reply == nil {
+				return []string{}
+			}
+			return reply(addr, args)
+		}), nil
+	}
+	cluster, err := radix.NewCluster([]string{"127.0.0.1:7000"}, radix.ClusterPoolFunc(poolFunc), radix.ClusterSyncEvery(time.Hour))
+	if err != nil {
+		t.Fatalf("NewCluster() error = %v", err)
+	}
+	t.Cleanup(func() { cluster.Close() })
+	return cluster
+}
+
+func slotKey(slot uint16, name string) string {
+	return "{" + crc16_slot_table[slot] + "}:" + name
+}
+
+func TestGroupPerNode(t *testing.T) {
+	cluster := newStubCluster(t, nil, nil)
+	tests := []struct {
+		name string
+		keys []string
+		// per node batch, the number of commands of each slot group
+		want map[string][]int
+	}{
+		{"single slot", []string{slotKey(1, "a"), slotKey(1, "b")}, map[string][]int{"127.0.0.1:7000": {2}}},
+		{"slots of one node", []string{slotKey(1, "a"), slotKey(2, "b"), slotKey(1, "c")}, map[string][]int{"127.0.0.1:7000": {2, 1}}},
+		{"slots of both nodes", []string{slotKey(1, "a"), slotKey(9000, "b"), slotKey(9000, "c"), slotKey(8191, "d")}, map[string][]int{"127.0.0.1:7000": {1, 1}, "127.0.0.1:7001": {2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds := []radix.CmdAction{}
+			for _, key := range tt.keys {
+				cmds = append(cmds, radix.Cmd(nil, "ZRANGE", key, "0", "1", "BYSCORE"))
+			}
+			batches, err := groupPerNode(cluster, cmds)
+			if err != nil {
+				t.Fatalf("groupPerNode() error = %v", err)
+			}
+			got := map[string][]int{}
+			for _, batch := range batches {
+				var commands uint64 = 0
+				for _, count := range batch.slotRangeCommands {
+					commands += count
+				}
+				for _, group := range batch.groups {
+					got[batch.addr] = append(got[batch.addr], len(group.cmds))
+					commands -= uint64(len(group.cmds))
+				}
+				if commands != 0 {
+					t.Errorf("node %s slot range commands do not add up to its slot groups", batch.addr)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupPerNode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupPerNodeWithoutKeys(t *testing.T) {
+	cluster := newStubCluster(t, nil, nil)
+	if _, err := groupPerNode(cluster, []radix.CmdAction{radix.Cmd(nil, "PING")}); err == nil {
+		t.Errorf("groupPerNode() of a command without keys succeeded, want an error")
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mediocregopher/radix/v3"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// configs captured by the preflight checks. The ziplist-named configs are the pre 7.0 names of the listpack ones.
+var preflightConfigs = []string{"zset-max-listpack-entries", "zset-max-listpack-value", "zset-max-ziplist-entries", "zset-max-ziplist-value", "maxmemory", "maxmemory-policy"}
+
+// approximate per key memory overhead of each sorted set encoding, used to estimate the memory needed by -mode=load.
+// Both account for the keyspace dict entry, the key sds and the redisObject. A skiplist key also holds the zset, dict
+// and skiplist structs, along with the skiplist header node and its 32 levels.
+const listpackKeyOverhead = 64
+const skiplistKeyOverhead = 768
+
+// approximate per member memory overhead of each sorted set encoding, besides the member bytes. A listpack member only
+// adds the entry headers of the member and of its score, stored as a listpack integer or string. A skiplist member takes
+// a skiplist node with 1.33 levels on average (48 bytes), a dict entry and its bucket (32 bytes) and the member sds header.
+const listpackMemberOverhead = 12
+const skiplistMemberOverhead = 84
+
+// default sorted set listpack thresholds, used when CONFIG GET is not available
+const defaultZsetMaxListpackEntries = 128
+const defaultZsetMaxListpackValue = 64
+
+// serverEnv is the server version and configuration of a node, captured by the preflight checks.
+type serverEnv struct {
+	Addr            string
+	RedisVersion    string
+	RedisMode       string
+	OS              string
+	ClusterEnabled  bool
+	UsedMemory      int64
+	MaxMemory       int64
+	MaxMemoryPolicy string
+	// keys of the benchmark database
+	Keys   int64
+	Config map[string]string
+}
+
+// getInfo issues INFO for each of the sections, and returns the fields of all of them.
+// Each section is requested on its own, given that INFO only accepts multiple sections since Redis 7.0.
+func getInfo(client radix.Client, sections ...string) (map[string]string, error) {
+	fields := map[string]string{}
+	for _, section := range sections {
+		var info string
+		if err := client.Do(radix.Cmd(&info, "INFO", section)); err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(info, "\r\n") {
+			if kv := strings.SplitN(line, ":", 2); len(kv) == 2 {
+				fields[kv[0]] = kv[1]
+			}
+		}
+	}
+	return fields, nil
+}
+
+// parseInfoFields parses the comma separated name=value fields of an INFO entry,
+// e.g. the keys=10,expires=0,avg_ttl=0 entry of a database on INFO keyspace.
+func parseInfoFields(entry string) map[string]string {
+	fields := map[string]string{}
+	for _, field := range strings.Split(entry, ",") {
+		if kv := strings.SplitN(field, "=", 2); len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	return fields
+}
+
+func getServerEnv(client radix.Client, addr string, db int) (serverEnv, error) {
+	env := serverEnv{Addr: addr, Config: map[string]string{}}
+	info, err := getInfo(client, "server", "memory", "keyspace", "cluster")
+	if err != nil {
+		return env, err
+	}
+	env.RedisVersion = info["redis_version"]
+	env.RedisMode = info["redis_mode"]
+	env.OS = info["os"]
+	env.ClusterEnabled = info["cluster_enabled"] == "1"
+	env.UsedMemory, _ = strconv.ParseInt(info["used_memory"], 10, 64)
+	env.MaxMemory, _ = strconv.ParseInt(info["maxmemory"], 10, 64)
+	env.MaxMemoryPolicy = info["maxmemory_policy"]
+	env.Keys, _ = strconv.ParseInt(parseInfoFields(info[fmt.Sprintf("db%d", db)])["keys"], 10, 64)
+	// CONFIG might be disabled or renamed, e.g. on managed services, in which case only the INFO fields are captured
+	for _, name := range preflightConfigs {
+		var reply map[string]string
+		if err := client.Do(radix.Cmd(&reply, "CONFIG", "GET", name)); err != nil {
+			log.Printf("Unable to capture the server configs of %s, CONFIG GET failed: %v", addr, err)
+			break
+		}
+		if value, found := reply[name]; found {
+			env.Config[name] = value
+		}
+	}
+	return env, nil
+}
+
+// getNodeClients returns the client of each cluster node on cluster connections, only of the primaries
+// when primariesOnly is set, and the given addresses and clients otherwise.
+func getNodeClients(conn radix.Client, addrs []string, clients []radix.Client, primariesOnly bool) ([]string, []radix.Client) {
+	cluster, isCluster := conn.(*radix.Cluster)
+	if !isCluster {
+		return addrs, clients
+	}
+	addrs, clients = nil, nil
+	nodes := cluster.Topo()
+	if primariesOnly {
+		nodes = nodes.Primaries()
+	}
+	for _, node := range nodes {
+		client, err := cluster.Client(node.Addr)
+		if err != nil {
+			log.Printf("Unable to get a client for node %s: %v", node.Addr, err)
+			continue
+		}
+		addrs = append(addrs, node.Addr)
+		clients = append(clients, client)
+	}
+	return addrs, clients
+}
+
+// getServerEnvs captures the environment of each primary on cluster connections,
+// and of each of the given addresses otherwise.
+func getServerEnvs(conn radix.Client, addrs []string, clients []radix.Client, db int) []serverEnv {
+	envs := []serverEnv{}
+	addrs, clients = getNodeClients(conn, addrs, clients, true)
+	for i, client := range clients {
+		env, err := getServerEnv(client, addrs[i], db)
+		if err != nil {
+			log.Printf("Received an error while issuing INFO on %s: %v", addrs[i], err)
+			continue
+		}
+		envs = append(envs, env)
+	}
+	return envs
+}
+
+// memberSizeProbs returns the probability of each generated member size, starting at the returned minimum size.
+// The zipfian probabilities follow rand.Zipf, where P(k) is proportional to (1+k)^-s.
+func memberSizeProbs(memberConfig memberGeneratorConfig) (uint64, []float64) {
+	switch {
+	case memberConfig.format == memberFormatUUID:
+		return 36, []float64{1}
+	case memberConfig.sizeDistribution == memberSizeUniform:
+		probs := make([]float64, memberConfig.sizeMax-memberConfig.sizeMin+1)
+		for k := range probs {
+			probs[k] = 1 / float64(len(probs))
+		}
+		return memberConfig.sizeMin, probs
+	case memberConfig.sizeDistribution == memberSizeZipfian:
+		probs := make([]float64, memberConfig.sizeMax-memberConfig.sizeMin+1)
+		total := 0.0
+		for k := range probs {
+			probs[k] = math.Pow(1+float64(k), -memberConfig.zipfSkew)
+			total += probs[k]
+		}
+		for k := range probs {
+			probs[k] /= total
+		}
+		return memberConfig.sizeMin, probs
+	}
+	return memberConfig.size, []float64{1}
+}
+
+// generatedMemberSize returns the size of a member generated with the given size, given that the
+// prefixed ids always hold the prefix and at least one digit.
+func generatedMemberSize(memberConfig memberGeneratorConfig, size uint64) uint64 {
+	if memberConfig.format == memberFormatPrefixedId && size <= uint64(len(memberConfig.prefix)) {
+		return uint64(len(memberConfig.prefix)) + 1
+	}
+	return size
+}
+
+// getConfigThresholds returns the sorted set listpack thresholds of a server config, falling back
+// to the ziplist-named configs of the pre 7.0 servers and then to the defaults.
+func getConfigThresholds(config map[string]string) zsetEncodingThresholds {
+	for _, names := range [][2]string{{"zset-max-listpack-entries", "zset-max-listpack-value"}, {"zset-max-ziplist-entries", "zset-max-ziplist-value"}} {
+		entries, entriesErr := strconv.ParseUint(config[names[0]], 10, 64)
+		value, valueErr := strconv.ParseUint(config[names[1]], 10, 64)
+		if entriesErr == nil && valueErr == nil {
+			return zsetEncodingThresholds{maxEntries: entries, maxValue: value}
+		}
+	}
+	return zsetEncodingThresholds{maxEntries: defaultZsetMaxListpackEntries, maxValue: defaultZsetMaxListpackValue}
+}
+
+// estimateLoadBytes estimates the memory needed by -mode=load. The number of elements of each key is uniform
+// on [elementsMin, elementsMax), and a key is only kept as a listpack while it holds up to the entries threshold
+// elements, none of them larger than the value threshold. The per key and per member overheads are approximations.
+func estimateLoadBytes(keys uint64, elementsMin uint64, elementsMax uint64, memberConfig memberGeneratorConfig, thresholds zsetEncodingThresholds) uint64 {
+	minSize, probs := memberSizeProbs(memberConfig)
+	meanMemberSize, largeMemberProb := 0.0, 0.0
+	for k, prob := range probs {
+		size := generatedMemberSize(memberConfig, minSize+uint64(k))
+		meanMemberSize += float64(size) * prob
+		if size > thresholds.maxValue {
+			largeMemberProb += prob
+		}
+	}
+	if elementsMax <= elementsMin {
+		elementsMax = elementsMin + 1
+	}
+	keyBytes := 0.0
+	for elements := elementsMin; elements < elementsMax; elements++ {
+		listpackProb := 0.0
+		if elements <= thresholds.maxEntries {
+			listpackProb = math.Pow(1-largeMemberProb, float64(elements))
+		}
+		listpackBytes := listpackKeyOverhead + float64(elements)*(meanMemberSize+listpackMemberOverhead)
+		skiplistBytes := skiplistKeyOverhead + float64(elements)*(meanMemberSize+skiplistMemberOverhead)
+		keyBytes += listpackProb*listpackBytes + (1-listpackProb)*skiplistBytes
+	}
+	return uint64(float64(keys) * keyBytes / float64(elementsMax-elementsMin))
+}
+
+// checkServerEnvs returns the warnings about the server environment not matching the benchmark options.
+// plannedBytes is the estimated memory needed by -mode=load, spread evenly across the servers, or 0 for the other modes.
+func checkServerEnvs(envs []serverEnv, clusterMode bool, isLoad bool, plannedBytes uint64) []string {
+	warnings := []string{}
+	var keys int64 = 0
+	for _, env := range envs {
+		keys += env.Keys
+		if env.ClusterEnabled && !clusterMode {
+			warnings = append(warnings, fmt.Sprintf("%s has cluster mode enabled but -oss-cluster is not set. The commands on keys served by other nodes will fail with MOVED", env.Addr))
+		}
+		if plannedBytes > 0 && env.MaxMemory > 0 {
+			nodeBytes := plannedBytes / uint64(len(envs))
+			if uint64(env.UsedMemory)+nodeBytes > uint64(env.MaxMemory) {
+				warnings = append(warnings, fmt.Sprintf("%s maxmemory %d is likely too small for the planned load. Used memory %d, estimated load %d, a rough estimate out of the sorted set encodings (maxmemory-policy %s)", env.Addr, env.MaxMemory, env.UsedMemory, nodeBytes, env.MaxMemoryPolicy))
+			}
+		}
+	}
+	if !isLoad && len(envs) > 0 && keys == 0 {
+		warnings = append(warnings, "the keyspace is empty. Populate it first with -mode=load")
+	}
+	return warnings
+}
+
+func printServerEnvs(envs []serverEnv, warnings []string) {
+	fmt.Printf("#################################################\n")
+	fmt.Printf("Server preflight checks\n")
+	for _, env := range envs {
+		fmt.Printf("    %s redis_version:%s redis_mode:%s os:%s used_memory:%d maxmemory:%d maxmemory_policy:%s keys:%d\n", env.Addr, env.RedisVersion, env.RedisMode, env.OS, env.UsedMemory, env.MaxMemory, env.MaxMemoryPolicy, env.Keys)
+	}
+	for _, warning := range warnings {
+		fmt.Printf("WARNING: %s\n", warning)
+	}
+	fmt.Printf("#################################################\n")
+}
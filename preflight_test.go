@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestParseInfoFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+		want  map[string]string
+	}{
+		{"keyspace", "keys=10,expires=0,avg_ttl=0", map[string]string{"keys": "10", "expires": "0", "avg_ttl": "0"}},
+		{"commandstats", "calls=5,usec=20,usec_per_call=4.00,rejected_calls=0,failed_calls=0", map[string]string{"calls": "5", "usec": "20", "usec_per_call": "4.00", "rejected_calls": "0", "failed_calls": "0"}},
+		{"latencystats", "p50=1.003,p99=3.007,p99.9=10.015", map[string]string{"p50": "1.003", "p99": "3.007", "p99.9": "10.015"}},
+		{"value with =", "a=b=c", map[string]string{"a": "b=c"}},
+		{"fields without value skipped", "a=1,b,c=3", map[string]string{"a": "1", "c": "3"}},
+		{"empty", "", map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseInfoFields(tt.entry); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseInfoFields(%q) = %v, want %v", tt.entry, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetConfigThresholds(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]string
+		want   zsetEncodingThresholds
+	}{
+		{"listpack configs", map[string]string{"zset-max-listpack-entries": "256", "zset-max-listpack-value": "32"}, zsetEncodingThresholds{256, 32}},
+		{"ziplist configs", map[string]string{"zset-max-ziplist-entries": "64", "zset-max-ziplist-value": "16"}, zsetEncodingThresholds{64, 16}},
+		{"CONFIG not available", map[string]string{}, zsetEncodingThresholds{defaultZsetMaxListpackEntries, defaultZsetMaxListpackValue}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getConfigThresholds(tt.config); got != tt.want {
+				t.Errorf("getConfigThresholds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemberSizeProbsMean(t *testing.T) {
+	tests := []struct {
+		name   string
+		config memberGeneratorConfig
+	}{
+		{"fixed", memberGeneratorConfig{sizeDistribution: memberSizeFixed, size: 10, format: memberFormatRandom, charset: memberCharsetLower}},
+		{"uniform", memberGeneratorConfig{sizeDistribution: memberSizeUniform, sizeMin: 5, sizeMax: 50, format: memberFormatRandom, charset: memberCharsetLower}},
+		{"zipfian", memberGeneratorConfig{sizeDistribution: memberSizeZipfian, sizeMin: 5, sizeMax: 200, zipfSkew: 1.2, format: memberFormatRandom, charset: memberCharsetLower}},
+		{"prefixed id", memberGeneratorConfig{sizeDistribution: memberSizeUniform, sizeMin: 1, sizeMax: 20, format: memberFormatPrefixedId, prefix: "id:", charset: memberCharsetLower}},
+		{"uuid", memberGeneratorConfig{sizeDistribution: memberSizeFixed, size: 10, format: memberFormatUUID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minSize, probs := memberSizeProbs(tt.config)
+			want := 0.0
+			for k, prob := range probs {
+				want += float64(generatedMemberSize(tt.config, minSize+uint64(k))) * prob
+			}
+			// the mean size of the members actually generated
+			g := newMemberGenerator(tt.config, rand.New(rand.NewSource(12345)))
+			const samples = 100000
+			total := 0
+			for i := 0; i < samples; i++ {
+				total += len(g.appendNext(nil, g.nextSize()))
+			}
+			if got := float64(total) / samples; math.Abs(got-want) > 0.05*want {
+				t.Errorf("mean member size %.2f, generated members mean size %.2f", want, got)
+			}
+		})
+	}
+}
+
+func TestEstimateLoadBytes(t *testing.T) {
+	thresholds := zsetEncodingThresholds{maxEntries: 128, maxValue: 64}
+	small := memberGeneratorConfig{sizeDistribution: memberSizeFixed, size: 10, format: memberFormatRandom}
+	large := memberGeneratorConfig{sizeDistribution: memberSizeFixed, size: 100, format: memberFormatRandom}
+	tests := []struct {
+		name                     string
+		elementsMin, elementsMax uint64
+		config                   memberGeneratorConfig
+		want                     uint64
+	}{
+		{"listpack", 10, 11, small, 1000 * (listpackKeyOverhead + 10*(10+listpackMemberOverhead))},
+		{"skiplist on the entries", 200, 201, small, 1000 * (skiplistKeyOverhead + 200*(10+skiplistMemberOverhead))},
+		{"skiplist on the value", 10, 11, large, 1000 * (skiplistKeyOverhead + 10*(100+skiplistMemberOverhead))},
+		{"empty elements range", 10, 10, small, 1000 * (listpackKeyOverhead + 10*(10+listpackMemberOverhead))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateLoadBytes(1000, tt.elementsMin, tt.elementsMax, tt.config, thresholds); got != tt.want {
+				t.Errorf("estimateLoadBytes() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"github.com/mediocregopher/radix/v3"
+	"github.com/mediocregopher/radix/v3/resp"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const reconnectInitialBackoff = 50 * time.Millisecond
+const reconnectMaxBackoff = 2 * time.Second
+
+// reconnectAttempts is the number of dial attempts of each reconnect.
+var reconnectAttempts = 5
+
+// set once all the benchmark connections were dialed
+var benchStarted int32
+
+// number of failed benchmark connections closed per address, which the next dials to the
+// address replace. Only those dials are accounted as reconnects.
+var failedConns = map[string]uint64{}
+var failedConnsMutex sync.Mutex
+
+var totalReconnects uint64
+var totalReconnectFailures uint64
+var totalTimeouts uint64
+
+// benchConn is a benchmark connection which tracks whether it failed, i.e. hit a network or protocol error,
+// in which case the pools close it and dial a replacement.
+type benchConn struct {
+	radix.Conn
+	addr   string
+	failed bool
+}
+
+// Encode and Decode flag the connection as failed on the same errors the radix pools discard connections on.
+func (c *benchConn) Encode(m resp.Marshaler) error {
+	err := c.Conn.Encode(m)
+	if netErr := net.Error(nil); errors.As(err, &netErr) {
+		c.failed = true
+	}
+	return err
+}
+
+func (c *benchConn) Decode(m resp.Unmarshaler) error {
+	err := c.Conn.Decode(m)
+	if err != nil && !errors.As(err, new(resp.ErrDiscarded)) {
+		c.failed = true
+	}
+	return err
+}
+
+func (c *benchConn) Do(a radix.Action) error {
+	return a.Run(c)
+}
+
+func (c *benchConn) Close() error {
+	if c.failed && atomic.LoadInt32(&benchStarted) == 1 {
+		failedConnsMutex.Lock()
+		failedConns[c.addr]++
+		failedConnsMutex.Unlock()
+	}
+	return c.Conn.Close()
+}
+
+// replacesFailedConn reports whether a dial to addr replaces a failed connection, consuming it.
+func replacesFailedConn(addr string) bool {
+	failedConnsMutex.Lock()
+	defer failedConnsMutex.Unlock()
+	if failedConns[addr] == 0 {
+		return false
+	}
+	failedConns[addr]--
+	return true
+}
+
+// dialBenchConnWithBackoff dials a benchmark connection. Once the benchmark started, the dials replacing
+// a failed connection are reconnects, which are retried with an exponential backoff up to reconnectAttempts times.
+// Other dials, e.g. the pools growing or the cluster connecting to a new node, are not accounted as reconnects.
+func dialBenchConnWithBackoff(network, addr string, opts []radix.DialOpt) (radix.Conn, error) {
+	if atomic.LoadInt32(&benchStarted) == 0 || !replacesFailedConn(addr) {
+		conn, err := dialBenchConn(network, addr, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &benchConn{Conn: conn, addr: addr}, nil
+	}
+	atomic.AddUint64(&totalReconnects, 1)
+	backoff := reconnectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		conn, err := dialBenchConn(network, addr, opts)
+		if err == nil {
+			return &benchConn{Conn: conn, addr: addr}, nil
+		}
+		if isTimeoutError(err) {
+			atomic.AddUint64(&totalTimeouts, 1)
+		}
+		if attempt >= reconnectAttempts {
+			atomic.AddUint64(&totalReconnectFailures, 1)
+			// the next dial to the address still replaces the failed connection
+			failedConnsMutex.Lock()
+			failedConns[addr]++
+			failedConnsMutex.Unlock()
+			log.Printf("Unable to reconnect to %s after %d attempts: %v", addr, attempt, err)
+			return nil, err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > reconnectMaxBackoff {
+			backoff = reconnectMaxBackoff
+		}
+	}
+}
+
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func recordTimeout(err error) {
+	if isTimeoutError(err) {
+		atomic.AddUint64(&totalTimeouts, 1)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"math/rand"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -18,35 +19,50 @@ import (
 
 var totalCommands uint64
 var totalAddedElements uint64
+var totalAddedMemberBytes uint64
 var totalErrors uint64
 var latencies *hdrhistogram.Histogram
 var replySizes []uint64
+var firstErrorOnce sync.Once
+
+// continueOnError counts the failed commands and keeps the benchmark running, instead of exiting on the first error.
+var continueOnError = false
 
 const Inf = rate.Limit(math.MaxFloat64)
 const charset = "abcdefghijklmnopqrstuvwxyz"
 
-func stringWithCharset(length int, charset string, r *rand.Rand) string {
-
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[r.Intn(len(charset))]
-	}
-	return string(b)
-}
-
 func main() {
 	host := flag.String("h", "127.0.0.1", "Server hostname.")
 	port := flag.Int("p", 12000, "Server port.")
+	socket := flag.String("s", "", "Server unix socket path. Overrides -h and -p.")
+	hosts := flag.String("hosts", "", "Comma separated list of host:port endpoints. Overrides -h and -p. On -oss-cluster all of them are used as seed nodes, so that the startup succeeds as long as one of them is reachable. Otherwise the clients are spread evenly across the endpoints, and the stats are reported per endpoint.")
 	rps := flag.Int64("rps", 0, "Max rps. If 0 no limit is applied and the DB is stressed up to maximum.")
 	password := flag.String("a", "", "Password for Redis Auth.")
+	user := flag.String("user", "", "ACL username for Redis Auth, used along with -a. If empty the default user is authenticated.")
+	db := flag.Int("db", 0, "Database number, selected on every new connection. Not supported on -oss-cluster.")
+	useTLS := flag.Bool("tls", false, "Use TLS connections, on the server nodes and on the sentinels.")
+	tlsCert := flag.String("cert", "", "Client certificate used on -tls connections. Requires -key.")
+	tlsKey := flag.String("key", "", "Client private key used on -tls connections. Requires -cert.")
+	tlsCACert := flag.String("cacert", "", "CA certificate used to verify the server certificates on -tls connections. If empty the system CA certificates are used.")
+	tlsSkipVerify := flag.Bool("tls-skip-verify", false, "Skip the server certificate verification on -tls connections.")
+	tlsSNI := flag.String("sni", "", "Server name indication used on -tls connections. If empty the host of each dialed address is used.")
+	connectTimeout := flag.Duration("connect-timeout", 10*time.Second, "Connect timeout. If 0 no timeout is applied.")
+	readTimeout := flag.Duration("read-timeout", 10*time.Second, "Read timeout. Replies taking longer fail with a timeout, which exits the benchmark unless -continue-on-error is set, in which case the timeout is counted and the connection is re-established. If 0 no timeout is applied.")
+	writeTimeout := flag.Duration("write-timeout", 10*time.Second, "Write timeout. If 0 no timeout is applied.")
+	reconnectMaxAttempts := flag.Int("reconnect-attempts", 5, "Number of attempts, with exponential backoff, to re-establish a dropped connection. Only used with -continue-on-error, as the benchmark otherwise exits on the first failed command.")
 	seed := flag.Int64("random-seed", 12345, "random seed to be used.")
 	clients := flag.Uint64("c", 50, "number of clients.")
+	connModel := flag.String("conn-model", connModelPool, fmt.Sprintf("Connection model of the clients. One of %v. `pool` shares a pool among all clients, which dials additional connections on demand. `dedicated` pins each client to its own connection. `shared` pins each group of -clients-per-conn clients to a single connection. On -oss-cluster `dedicated` and `shared` apply per node.", connModels))
+	clientsPerConn := flag.Uint64("clients-per-conn", 10, "Number of clients sharing each connection on -conn-model=shared.")
 	keyspacelen := flag.Uint64("r", 1000000, "keyspace length.")
-	keyspacestart := flag.Uint64("r-start", 0, "keyspace start.")
+	keyspacestart := flag.Uint64("r-start", 0, "keyspace start. Both -mode=load and -mode=query use the keyspace range [r-start ; r-start+r[.")
 	numberRequests := flag.Uint64("n", 10000000, "Total number of requests. Only used in case of -mode=query")
 	debug := flag.Int("debug", 0, "Client debug level.")
+	continueOnErrors := flag.Bool("continue-on-error", false, "Count the failed commands as errors and keep the benchmark running, instead of exiting on the first error. Always enabled with -sentinels, so that the failovers can be measured.")
 	multi := flag.Bool("multi", false, "Run each command in multi-exec.")
-	benchMode := flag.String("mode", "", "Bechmark mode. One of [load,query]. `load` will populate the db with sorted sets. `query` will run the zrangebylexscore command .")
+	benchMode := flag.String("mode", "", "Bechmark mode. One of [load,query,churn]. `load` will populate the db with sorted sets. `query` will run the zrangebylexscore command . `churn` will have each client repeatedly connect, run -churn-commands -query commands and disconnect.")
+	churnCommands := flag.Uint64("churn-commands", 10, "Number of -query commands issued on each connection of -mode=churn.")
+	churnSelect := flag.Bool("churn-select", false, "Issue SELECT -db on each connection of -mode=churn, after the AUTH. Always issued when -db is not 0.")
 	perKeyElmRangeStart := flag.Uint64("key-elements-min", 10, "Use zipfian random-sized items in the specified range (min-max).")
 	perKeyElmRangeEnd := flag.Uint64("key-elements-max", 100, "Use zipfian random-sized items in the specified range (min-max).")
 	perKeyElmDataSize := flag.Uint64("d", 10, "Data size of each sorted set element.")
@@ -54,7 +70,45 @@ func main() {
 	version := flag.Bool("v", false, "Output version and exit")
 	printReplyHistogram := flag.Bool("print-histogram", false, "Print reply histogram")
 	clusterMode := flag.Bool("oss-cluster", false, "Enable OSS cluster mode.")
-	query := flag.String("query", "zrangebyscore", "Query type.")
+	query := flag.String("query", "zrange-byscore", "Query type. One of [zrange-byscore,zrange-byscore-rev,zrange-withscores,zrevrangebylex,zscore]. zscore picks the queried members with an untimed ZRANDMEMBER, which requires Redis 6.2 or later, and whose replies are included in the -resp 3 reply stats.")
+	encodingAware := flag.Bool("encoding-aware", false, "Generate keys just below and just above the zset-max-listpack-entries/value thresholds, and report query latency broken down by encoding. Requires -member-format=random.")
+	memorySamples := flag.Uint64("memory-samples", 100, "Number of keys sampled via MEMORY USAGE after -mode=load, to report the memory footprint per key and per member. If 0 the memory footprint is not reported.")
+	memoryUsageSamples := flag.Int("memory-usage-samples", 5, "SAMPLES argument of the MEMORY USAGE issued after -mode=load, i.e. the number of members sampled per key. If 0 all the members are accounted.")
+	encodingSamples := flag.Uint64("encoding-samples", 100, "Number of keys to verify via OBJECT ENCODING after a -encoding-aware load.")
+	scoreDistribution := flag.String("score-distribution", scoreDistributionFloat, fmt.Sprintf("Score distribution of the loaded members. One of %v.", scoreDistributions))
+	scoreTiesCardinality := flag.Int64("score-ties-cardinality", 10, "Number of distinct scores used by -score-distribution=ties.")
+	scoreTimestampJitter := flag.Int64("score-timestamp-jitter", 60, "Max jitter in seconds applied to each score by -score-distribution=timestamp-jitter.")
+	scoreInfPercent := flag.Float64("score-inf-percent", 10.0, "Percentage of +inf/-inf scores generated by -score-distribution=inf.")
+	memberSizeDistribution := flag.String("member-size-distribution", memberSizeFixed, fmt.Sprintf("Size distribution of the loaded members. One of %v. `fixed` uses -d as the member size.", memberSizeDistributions))
+	memberSizeMin := flag.Uint64("member-size-min", 1, "Min member size used by the uniform and zipfian -member-size-distribution.")
+	memberSizeMax := flag.Uint64("member-size-max", 64, "Max member size used by the uniform and zipfian -member-size-distribution.")
+	memberSizeZipfSkew := flag.Float64("member-size-zipf-skew", 1.5, "Skew (s > 1) of the zipfian -member-size-distribution. Larger values favour smaller members.")
+	memberCharset := flag.String("member-charset", memberCharsetLower, fmt.Sprintf("Charset of the random members. One of [%s].", memberCharsetNames()))
+	memberFormat := flag.String("member-format", memberFormatRandom, fmt.Sprintf("Format of the loaded members. One of %v. `prefixed-id` generates <member-prefix><digits> members.", memberFormats))
+	memberPrefix := flag.String("member-prefix", "user:", "Member prefix used by -member-format=prefixed-id.")
+	keyDistribution := flag.String("key-distribution", keyDistributionUniform, fmt.Sprintf("Key access distribution of -mode=query. One of %v.", keyDistributions))
+	keyZipfSkew := flag.Float64("key-zipf-skew", 1.1, "Skew (s > 1) of the zipfian -key-distribution. Larger values concentrate the traffic on fewer keys.")
+	keyHotspotTraffic := flag.Float64("key-hotspot-traffic", 80.0, "Percentage of the traffic sent to the hot set by -key-distribution=hotspot.")
+	keyHotspotKeys := flag.Float64("key-hotspot-keys", 20.0, "Percentage of the keyspace that is part of the hot set of -key-distribution=hotspot.")
+	keyGaussianStddev := flag.Float64("key-gaussian-stddev", 1.0, "Standard deviation, as a percentage of the keyspace, of -key-distribution=gaussian.")
+	keyGaussianCenterStep := flag.Uint64("key-gaussian-center-step", 1, "Number of keys the center of -key-distribution=gaussian moves on each request.")
+	keyPrefix := flag.String("key-prefix", defaultKeyNamePrefix, "Prefix of the benchmark key names.")
+	keyHashTag := flag.Bool("key-hash-tag", true, "Include the slot hash tag on the benchmark key names. Pipelined query keys only map to a single slot when enabled.")
+	keyPadding := flag.Int("key-padding", 0, "Zero pad the key numbers to the specified width. If 0 no padding is applied.")
+	keyFormat := flag.String("key-format", "", "Custom key name format, supporting the ${prefix}, ${tag} and ${n} variables. Overrides -key-prefix and -key-hash-tag. The keys only share a cluster slot per ${tag} when it is wrapped as the {${tag}} hash tag. If empty defaults to ${prefix}:{${tag}}:${n}.")
+	slotRange := flag.String("slot-range", "", "Restrict the generated keys to the comma separated list of inclusive slot ranges, e.g. 0-5460,10923-16383.")
+	clusterNode := flag.String("cluster-node", "", "Restrict the generated keys to the slots served by the given primary (address or node ID), resolved via CLUSTER SLOTS. Requires -oss-cluster.")
+	hotSlot := flag.Int("hot-slot", -1, "Restrict the generated keys to a single slot. If -1 no restriction is applied.")
+	clusterSyncInterval := flag.Duration("cluster-sync-interval", 5*time.Second, "Interval between periodic refreshes of the cluster slot map, in -oss-cluster mode. The slot map is also refreshed on MOVED redirects.")
+	readReplicas := flag.Bool("read-from-replica", false, "Route the -mode=query commands to replicas. On -oss-cluster the replicas are discovered via CLUSTER SLOTS and READONLY is issued on their connections. Otherwise the replicas are set via -replicas.")
+	replicas := flag.String("replicas", "", "Comma separated list of host:port replica addresses used by -read-from-replica outside -oss-cluster. The clients are spread evenly across the replicas.")
+	sentinels := flag.String("sentinels", "", "Comma separated list of host:port sentinel addresses. When set the primary is discovered via the sentinels instead of -h/-p, and followed through failovers.")
+	sentinelPrimary := flag.String("sentinel-master", "mymaster", "Name of the primary monitored by the -sentinels.")
+	sentinelPassword := flag.String("sentinel-password", "", "Password for the sentinels Auth. If empty no Auth is issued to the sentinels.")
+	respVersion := flag.Int("resp", 2, "RESP protocol version. One of [2,3]. RESP3 is negotiated via HELLO 3 on every connection, and requires Redis 6.0 or later.")
+	serverStatsInterval := flag.Duration("server-stats-interval", 0, "Interval between the INFO samples taken on every server node during the run, e.g. 1s, to report the server side ops/sec, CPU per op, memory growth and per command latency. The samples add INFO calls to the server, whose CPU time is included in the CPU per op. If 0 no samples are taken.")
+	jsonOutputFile := flag.String("json-out-file", "", "Results file. If empty will not save.")
+	memberPoolSize := flag.Uint64("member-pool-size", 0, "Size in bytes of the pre-generated random data each client slices random members from. Reduces the client CPU needed to generate members. If 0 every member is generated byte by byte.")
 
 	flag.Parse()
 
@@ -67,13 +121,176 @@ func main() {
 		fmt.Fprintf(os.Stdout, "redis-zbench-go (git_sha1:%s%s)\n", git_sha, git_dirty_str)
 		os.Exit(0)
 	}
-	if *benchMode != "load" && *benchMode != "query" {
-		log.Fatal("Please specify a valid -mode option. Either `load` or `query`")
+	if *benchMode != "load" && *benchMode != "query" && *benchMode != "churn" {
+		log.Fatal("Please specify a valid -mode option. One of `load`, `query` or `churn`")
+	}
+	if *query != "zrange-byscore" && *query != "zrange-byscore-rev" && *query != "zrange-withscores" && *query != "zrevrangebylex" && *query != "zscore" {
+		log.Fatal("Please specify a valid -query option. One of `zrange-byscore`, `zrange-byscore-rev`, `zrange-withscores`, `zrevrangebylex` or `zscore`")
+	}
+	if !isValidScoreDistribution(*scoreDistribution) {
+		log.Fatalf("Please specify a valid -score-distribution option. One of %v", scoreDistributions)
+	}
+	scoreConfig := scoreGeneratorConfig{
+		distribution:    *scoreDistribution,
+		tiesCardinality: *scoreTiesCardinality,
+		timestampJitter: *scoreTimestampJitter,
+		infPercent:      *scoreInfPercent,
+		timestampBase:   time.Now().Unix(),
+	}
+	memberConfig := memberGeneratorConfig{
+		sizeDistribution: *memberSizeDistribution,
+		size:             *perKeyElmDataSize,
+		sizeMin:          *memberSizeMin,
+		sizeMax:          *memberSizeMax,
+		zipfSkew:         *memberSizeZipfSkew,
+		charset:          *memberCharset,
+		format:           *memberFormat,
+		prefix:           *memberPrefix,
+		poolSize:         *memberPoolSize,
+	}
+	if err := memberConfig.validate(); err != nil {
+		log.Fatalf("Please specify valid member generation options: %v", err)
+	}
+	// the encoding aware keys set the member sizes around zset-max-listpack-value, which the uuid and prefixed-id members do not honour
+	if *encodingAware && *memberFormat != memberFormatRandom {
+		log.Fatalf("-encoding-aware requires -member-format=%s", memberFormatRandom)
+	}
+	keyConfig := keyGeneratorConfig{
+		distribution:          *keyDistribution,
+		keyspaceLen:           *keyspacelen,
+		zipfSkew:              *keyZipfSkew,
+		hotspotTrafficPercent: *keyHotspotTraffic,
+		hotspotKeysPercent:    *keyHotspotKeys,
+		gaussianStddevPercent: *keyGaussianStddev,
+		gaussianCenterStep:    *keyGaussianCenterStep,
+	}
+	if err := keyConfig.validate(); err != nil {
+		log.Fatalf("Please specify valid key distribution options: %v", err)
+	}
+	keyNameFormat := *keyFormat
+	if keyNameFormat == "" {
+		keyNameFormat = buildKeyNameFormat(*keyPrefix, *keyHashTag)
+	}
+	keyTemplate, err := parseKeyNameTemplate(keyNameFormat, *keyPrefix, *keyPadding)
+	if err != nil {
+		log.Fatalf("Please specify a valid key naming scheme: %v", err)
+	}
+	// the package level benchmark settings, from the key naming and slots to the RESP protocol, the error
+	// handling, the reconnects and the replica reads, are set once below, before any client goroutine starts
+	benchKeyTemplate = keyTemplate
+	slotTargets := 0
+	for _, target := range []bool{*slotRange != "", *clusterNode != "", *hotSlot >= 0} {
+		if target {
+			slotTargets++
+		}
+	}
+	if slotTargets > 1 {
+		log.Fatal("Please specify only one of -slot-range, -cluster-node or -hot-slot")
+	}
+	if slotTargets > 0 && !benchKeyTemplate.hasHashTag() {
+		log.Fatalf("Targeting slots requires the {${%s}} hash tag on the key names, with no other { ahead of it", keyNameVarTag)
+	}
+	if *clusterNode != "" && !*clusterMode {
+		log.Fatal("-cluster-node requires -oss-cluster")
+	}
+	if *slotRange != "" {
+		benchKeySlots, err = parseSlotRanges(*slotRange)
+		if err != nil {
+			log.Fatalf("Please specify a valid -slot-range option: %v", err)
+		}
+	}
+	if *hotSlot >= 0 {
+		benchKeySlots, err = parseSlotRanges(strconv.Itoa(*hotSlot))
+		if err != nil {
+			log.Fatalf("Please specify a valid -hot-slot option: %v", err)
+		}
+	}
+	if *db < 0 {
+		log.Fatal("Please specify a valid -db option. The database number can't be negative")
+	}
+	if *db != 0 && *clusterMode {
+		log.Fatal("-db is not supported on -oss-cluster, given that Redis Cluster only supports the database 0")
+	}
+	if *memoryUsageSamples < 0 {
+		log.Fatal("Please specify a valid -memory-usage-samples option. The number of samples can't be negative")
+	}
+	if *clusterSyncInterval <= 0 {
+		log.Fatal("Please specify a -cluster-sync-interval larger than 0")
 	}
 	isLoad := false
 	if *benchMode == "load" {
 		isLoad = true
 	}
+	isChurn := *benchMode == "churn"
+	if *readReplicas && (isLoad || isChurn) {
+		log.Fatal("-read-from-replica is only supported on -mode=query")
+	}
+	if isChurn {
+		if *clusterMode || *sentinels != "" || *respVersion != 2 || *multi || *pipeline != 1 {
+			log.Fatal("-mode=churn is not supported along with -oss-cluster, -sentinels, -resp 3, -multi or -pipeline")
+		}
+		if *churnCommands == 0 {
+			log.Fatal("Please specify a -churn-commands larger than 0")
+		}
+	}
+	if *replicas != "" && (!*readReplicas || *clusterMode) {
+		log.Fatal("-replicas requires -read-from-replica, and is not supported on -oss-cluster, where the replicas are discovered via CLUSTER SLOTS")
+	}
+	if *socket != "" && (*clusterMode || *sentinels != "") {
+		log.Fatal("-s is not supported along with -oss-cluster or -sentinels")
+	}
+	var endpointAddrs []string = nil
+	if *hosts != "" {
+		if *socket != "" || *sentinels != "" {
+			log.Fatal("-hosts is not supported along with -s or -sentinels")
+		}
+		if *readReplicas && !*clusterMode {
+			log.Fatal("-hosts is not supported along with -read-from-replica outside -oss-cluster")
+		}
+		endpointAddrs, err = parseEndpointAddrs(*hosts)
+		if err != nil {
+			log.Fatalf("Please specify a valid -hosts option: %v", err)
+		}
+	}
+	if *respVersion != 2 && *respVersion != 3 {
+		log.Fatal("Please specify a valid -resp option. Either 2 or 3")
+	}
+	respProtocol = *respVersion
+	continueOnError = *continueOnErrors || *sentinels != ""
+	if !stringInSlice(*connModel, connModels) {
+		log.Fatalf("Please specify a valid -conn-model option. One of %v", connModels)
+	}
+	if *connModel == connModelShared && *clientsPerConn == 0 {
+		log.Fatal("Please specify a -clients-per-conn larger than 0")
+	}
+	if *connModel != connModelPool && *sentinels != "" {
+		log.Fatalf("-conn-model=%s is not supported along with -sentinels", *connModel)
+	}
+	// connections per node of the benchmark pools. With the dedicated and shared models the standalone
+	// pools are only used for control commands, while each client is pinned to its own connection.
+	// The same goes for -mode=churn, where each client dials its own connections.
+	nodeConns := connModelNodeConns(*connModel, *clients, *clientsPerConn)
+	standalonePoolSize := *clients
+	if *connModel != connModelPool || isChurn {
+		standalonePoolSize = 1
+	}
+	var sentinelAddrs []string = nil
+	if *sentinels != "" {
+		if *clusterMode || *readReplicas {
+			log.Fatal("-sentinels is not supported along with -oss-cluster or -read-from-replica")
+		}
+		sentinelAddrs, err = parseSentinelAddrs(*sentinels)
+		if err != nil {
+			log.Fatalf("Please specify a valid -sentinels option: %v", err)
+		}
+	}
+	var replicaAddrs []string = nil
+	if *readReplicas && !*clusterMode {
+		replicaAddrs, err = parseReplicaAddrs(*replicas)
+		if err != nil {
+			log.Fatalf("Please specify a valid -replicas option: %v", err)
+		}
+	}
 	var requestRate = Inf
 	var requestBurst = 1
 	useRateLimiter := false
@@ -93,10 +310,46 @@ func main() {
 	latencies = hdrhistogram.New(1, 90000000, 3)
 	opts := make([]radix.DialOpt, 0)
 	replySizes = make([]uint64, *perKeyElmRangeEnd*100)
-	if *password != "" {
+	if *user != "" {
+		opts = append(opts, radix.DialAuthUser(*user, *password))
+	} else if *password != "" {
 		opts = append(opts, radix.DialAuthPass(*password))
 	}
+	// connOpts are the dial options besides AUTH, which -mode=churn issues on its own
+	// the timeouts are always set, as radix otherwise applies its 10 seconds default to each of them
+	connOpts := []radix.DialOpt{
+		radix.DialConnectTimeout(*connectTimeout),
+		radix.DialReadTimeout(*readTimeout),
+		radix.DialWriteTimeout(*writeTimeout),
+	}
+	if *reconnectMaxAttempts < 1 {
+		log.Fatal("Please specify a -reconnect-attempts of at least 1")
+	}
+	reconnectAttempts = *reconnectMaxAttempts
+	var tlsOpts []radix.DialOpt = nil
+	if *useTLS {
+		tlsConfig, err := getTLSConfig(*tlsCert, *tlsKey, *tlsCACert, *tlsSkipVerify, *tlsSNI)
+		if err != nil {
+			log.Fatalf("Please specify valid TLS options: %v", err)
+		}
+		tlsOpts = append(tlsOpts, radix.DialUseTLS(tlsConfig))
+		connOpts = append(connOpts, tlsOpts...)
+	} else if *tlsCert != "" || *tlsKey != "" || *tlsCACert != "" || *tlsSkipVerify || *tlsSNI != "" {
+		log.Fatal("-cert, -key, -cacert, -tls-skip-verify and -sni require -tls")
+	}
+	opts = append(opts, connOpts...)
+	if *db != 0 {
+		opts = append(opts, radix.DialSelectDB(*db))
+	}
 	connectionStr := fmt.Sprintf("%s:%d", *host, *port)
+	network := "tcp"
+	if *socket != "" {
+		connectionStr = *socket
+		network = "unix"
+	}
+	if endpointAddrs != nil {
+		connectionStr = endpointAddrs[0]
+	}
 	stopChan := make(chan struct{})
 	// a WaitGroup for the goroutines to tell us they've stopped
 	wg := sync.WaitGroup{}
@@ -104,15 +357,190 @@ func main() {
 	fmt.Printf("Using redis-zbench-go (git_sha1:%s%s)\n", git_sha, git_dirty_str)
 	fmt.Printf("Total clients: %d. Commands per client: %d Total commands: %d\n", *clients, samplesPerClient, totalCmds)
 	fmt.Printf("Using random seed: %d\n", *seed)
+	if respProtocol == 3 {
+		fmt.Printf("Using the RESP3 protocol\n")
+	}
+	if *db != 0 {
+		fmt.Printf("Using database %d\n", *db)
+	}
+	if *useTLS {
+		fmt.Printf("Using TLS connections. Client certificate: %t. Server certificate verification: %t\n", *tlsCert != "", !*tlsSkipVerify)
+	}
 	if isLoad {
 		fmt.Printf("Each ZSET contains between %d and %d elements.\n", *perKeyElmRangeStart, *perKeyElmRangeEnd)
 		fmt.Printf("Keyspace range: %d keys. [%d ; %d]\n", *keyspacelen, uint64(*keyspacestart), keyspaceend)
+		fmt.Printf("Score distribution: %s\n", *scoreDistribution)
+		fmt.Printf("Member format: %s. Member size distribution: %s. Member charset: %s\n", *memberFormat, *memberSizeDistribution, *memberCharset)
+	} else {
+		fmt.Printf("Keyspace range: %d keys. [%d ; %d]\n", *keyspacelen, uint64(*keyspacestart), keyspaceend)
+		fmt.Printf("Key access distribution: %s\n", *keyDistribution)
+	}
+	churn := churnConfig{
+		network:         network,
+		addr:            connectionStr,
+		opts:            connOpts,
+		user:            *user,
+		password:        *password,
+		selectDB:        *churnSelect || *db != 0,
+		db:              *db,
+		commandsPerConn: *churnCommands,
+		query:           *query,
+		endpointStats:   endpointAddrs != nil,
+	}
+	if isChurn {
+		fmt.Printf("Connection churn. Each connection issues %d commands. AUTH: %t. SELECT: %t\n", churn.commandsPerConn, churn.user != "" || churn.password != "", churn.selectDB)
 	}
 	var cluster *radix.Cluster
 	if *clusterMode {
-		cluster = getOSSClusterConn(connectionStr, opts, *clients)
+		seedAddrs := []string{connectionStr}
+		if endpointAddrs != nil {
+			seedAddrs = endpointAddrs
+			fmt.Printf("Using %d cluster seed nodes: %v\n", len(seedAddrs), seedAddrs)
+		}
+		cluster = getOSSClusterConn(seedAddrs, opts, nodeConns, *clusterSyncInterval, *readReplicas, connModelPoolOpts(*connModel)...)
+		if *readReplicas {
+			primaries := cluster.Topo().Primaries()
+			clusterReplicas := getClusterReplicas(cluster.Topo())
+			if len(clusterReplicas) == 0 {
+				log.Fatal("-read-from-replica requires replicas, but none were found via CLUSTER SLOTS")
+			}
+			for _, primary := range primaries {
+				if len(clusterReplicas[primary.Addr]) == 0 {
+					log.Printf("Primary %s has no replicas. Its slots will be read from the primary", primary.Addr)
+				}
+			}
+			fmt.Printf("Reading from the replicas. Found replicas for %d out of %d primaries\n", len(clusterReplicas), len(primaries))
+			readFromReplica = true
+		}
+		if *clusterNode != "" {
+			benchKeySlots, err = getClusterNodeSlots(cluster, *clusterNode)
+			if err != nil {
+				log.Fatalf("Please specify a valid -cluster-node option: %v", err)
+			}
+		}
+	}
+	if benchKeySlots != nil {
+		fmt.Printf("Restricting the generated keys to %d slots. [%d ; %d]\n", len(benchKeySlots), benchKeySlots[0], benchKeySlots[len(benchKeySlots)-1])
+	}
+	fmt.Printf("Key name format: %s (e.g. %s)\n", keyNameFormat, getBenchKeyName(*keyspacestart))
+	var connectionPool radix.Client
+	// standalone connections expected on CLIENT LIST
+	var expectedConns uint64 = 0
+	var sentinel *radix.Sentinel
+	// standalone pools of the -hosts endpoints, the first one being connectionPool
+	var endpointPools []radix.Client = nil
+	if sentinelAddrs != nil {
+		sentinelOpts := append(make([]radix.DialOpt, 0), tlsOpts...)
+		if *sentinelPassword != "" {
+			sentinelOpts = append(sentinelOpts, radix.DialAuthPass(*sentinelPassword))
+		}
+		sentinel = getSentinelConn(sentinelAddrs, *sentinelPrimary, opts, sentinelOpts, *clients)
+		connectionStr, _ = sentinel.Addrs()
+		fmt.Printf("Primary %s discovered via the sentinels %v: %s\n", *sentinelPrimary, sentinelAddrs, connectionStr)
+		go watchSentinelPrimary(sentinel, stopChan)
+		connectionPool = sentinel
+		expectedConns += *clients
+	} else if !*clusterMode && endpointAddrs != nil {
+		endpointClients := (*clients + uint64(len(endpointAddrs)) - 1) / uint64(len(endpointAddrs))
+		if *connModel != connModelPool || isChurn {
+			endpointClients = 1
+		}
+		for _, endpointAddr := range endpointAddrs {
+			endpointPools = append(endpointPools, getStandaloneConn(network, endpointAddr, opts, endpointClients))
+			expectedConns += endpointClients
+		}
+		connectionPool = endpointPools[0]
+		fmt.Printf("Spreading the clients across %d endpoints: %v\n", len(endpointAddrs), endpointAddrs)
+	} else if !*clusterMode {
+		connectionPool = getStandaloneConn(network, connectionStr, opts, standalonePoolSize)
+		expectedConns += standalonePoolSize
+		if *socket != "" {
+			fmt.Printf("Connecting via the unix socket %s\n", *socket)
+		}
+	}
+	var replicaPools []radix.Client = nil
+	for _, replicaAddr := range replicaAddrs {
+		replicaClients := (*clients + uint64(len(replicaAddrs)) - 1) / uint64(len(replicaAddrs))
+		if *connModel != connModelPool {
+			replicaClients = 1
+		}
+		replicaPools = append(replicaPools, getStandaloneConn("tcp", replicaAddr, opts, replicaClients))
+		expectedConns += replicaClients
+	}
+	if replicaPools != nil {
+		fmt.Printf("Reading from %d replicas: %v\n", len(replicaAddrs), replicaAddrs)
+	}
+	if *user != "" {
+		var aclConn radix.Client = connectionPool
+		if *clusterMode {
+			aclConn = cluster
+		}
+		aclKey := getBenchKeyName(*keyspacestart)
+		aclCmds := append(getACLPreflightCmds(isLoad, *query, *multi, aclKey), getACLReportCmds(isLoad, *encodingAware, *encodingSamples > 0, *memorySamples > 0, aclKey)...)
+		aclWarnings, err := checkACLPermissions(aclConn, *user, aclKey, aclCmds)
+		if err != nil {
+			log.Fatalf("The ACL permissions preflight check failed: %v", err)
+		}
+		fmt.Printf("ACL permissions preflight check passed for user %s\n", *user)
+		for _, warning := range aclWarnings {
+			fmt.Printf("WARNING: %s\n", warning)
+		}
+	}
+	preflightAddrs, preflightClients := []string{connectionStr}, []radix.Client{connectionPool}
+	for i := 1; i < len(endpointPools); i++ {
+		preflightAddrs, preflightClients = append(preflightAddrs, endpointAddrs[i]), append(preflightClients, endpointPools[i])
 	}
-	var connectionPool *radix.Pool = getStandaloneConn(connectionStr, opts, *clients)
+	var preflightConn radix.Client = connectionPool
+	if *clusterMode {
+		preflightConn = cluster
+	}
+	serverEnvs := getServerEnvs(preflightConn, preflightAddrs, preflightClients, *db)
+	var plannedLoadBytes uint64 = 0
+	if isLoad && len(serverEnvs) > 0 {
+		plannedLoadBytes = estimateLoadBytes(*keyspacelen, *perKeyElmRangeStart, *perKeyElmRangeEnd, memberConfig, getConfigThresholds(serverEnvs[0].Config))
+	}
+	preflightWarnings := checkServerEnvs(serverEnvs, *clusterMode, isLoad, plannedLoadBytes)
+	printServerEnvs(serverEnvs, preflightWarnings)
+	var memoryBefore memoryState
+	if isLoad && *memorySamples > 0 {
+		memoryBefore = getMemoryState(preflightConn, preflightAddrs, preflightClients, *db)
+	}
+	var serverStats *serverStatsSampler = nil
+	if *serverStatsInterval > 0 {
+		statsAddrs, _ := getNodeClients(preflightConn, preflightAddrs, preflightClients, false)
+		statsNetwork := network
+		if *clusterMode {
+			// the cluster nodes are reached over tcp, as the addresses announced on the topology
+			statsNetwork = "tcp"
+		}
+		serverStats = newServerStatsSampler(statsNetwork, statsAddrs, opts, *serverStatsInterval)
+		go serverStats.run()
+	}
+	var encodingThresholds *zsetEncodingThresholds = nil
+	if *encodingAware {
+		if *clusterMode {
+			encodingThresholds = getZsetEncodingThresholds(cluster)
+		} else {
+			encodingThresholds = getZsetEncodingThresholds(connectionPool)
+		}
+		// the latency of a pipeline is charged to the encoding of its first key. The pipelined keys are one slot
+		// cycle apart, so they only share the parity, i.e. the encoding, of the first key when the cycle is even
+		if !isLoad && *pipeline > 1 && benchKeySlotsCount()%2 != 0 {
+			log.Fatalf("-encoding-aware with -pipeline larger than 1 requires an even number of targeted slots, got %d", benchKeySlotsCount())
+		}
+		encodingLatencies = newEncodingLatencies()
+		// the encoding aware keys are sized from zset-max-listpack-entries instead of the element range
+		if replySizesLen := encodingThresholds.maxEntries + 2; replySizesLen > uint64(len(replySizes)) {
+			replySizes = make([]uint64, replySizesLen)
+		}
+		fmt.Printf("Encoding aware dataset. zset-max-listpack-entries: %d zset-max-listpack-value: %d\n", encodingThresholds.maxEntries, encodingThresholds.maxValue)
+	}
+	conns := newClientConns(*connModel, *clientsPerConn, opts)
+	if *connModel != connModelPool {
+		expectedConns += nodeConns
+		fmt.Printf("Connection model: %s. Connections per node: %d\n", *connModel, nodeConns)
+	}
+	clientUsageStart := getClientUsage()
 	for client_id := 1; uint64(client_id) <= *clients; client_id++ {
 		wg.Add(1)
 		keyspace_client_start := uint64(*keyspacestart) + (uint64(client_id-1) * samplesPerClient)
@@ -120,42 +548,85 @@ func main() {
 		if uint64(client_id) == *clients {
 			keyspace_client_end = keyspaceend
 		}
+		clientKeyConfig := keyConfig
+		// spread the sequential scans of the clients evenly across the keyspace
+		clientKeyConfig.sequentialStart = uint64(client_id-1) * *keyspacelen / *clients
+		clientNetwork, clientAddr, clientPool := network, connectionStr, connectionPool
+		if replicaPools != nil {
+			replica := (client_id - 1) % len(replicaPools)
+			clientNetwork, clientAddr, clientPool = "tcp", replicaAddrs[replica], replicaPools[replica]
+		}
+		if endpointPools != nil {
+			endpoint := (client_id - 1) % len(endpointPools)
+			clientAddr, clientPool = endpointAddrs[endpoint], endpointPools[endpoint]
+		}
+		var clientConn radix.Client = clientPool
+		if !*clusterMode && !isChurn {
+			clientConn = conns.get(uint64(client_id), clientNetwork, clientAddr, clientPool)
+		}
+		if endpointPools != nil {
+			clientConn = &endpointClient{Client: clientConn, addr: clientAddr}
+		}
+		clientChurn := churn
+		clientChurn.addr = clientAddr
 		if isLoad {
 			if *clusterMode {
-				go loadGoRoutime(cluster, keyspace_client_start, keyspace_client_end, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed + int64(client_id))
+				go loadGoRoutime(cluster, keyspace_client_start, keyspace_client_end, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), encodingThresholds, scoreConfig, memberConfig)
 			} else {
-				go loadGoRoutime(connectionPool, keyspace_client_start, keyspace_client_end, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed + int64(client_id))
+				go loadGoRoutime(clientConn, keyspace_client_start, keyspace_client_end, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), encodingThresholds, scoreConfig, memberConfig)
 			}
+		} else if isChurn {
+			go churnGoRoutime(clientChurn, *keyspacestart, samplesPerClient, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), clientKeyConfig)
 		} else {
 			switch *query {
 			case "zrange-byscore-rev":
 				if *clusterMode {
-					go queryGoRoutimeZrevrangeByScore(cluster, *multi, uint64(*keyspacelen), samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed + int64(client_id))
+					go queryGoRoutimeZrevrangeByScore(cluster, *multi, *keyspacestart, *keyspacelen, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), encodingThresholds, clientKeyConfig)
 				} else {
-					go queryGoRoutimeZrevrangeByScore(connectionPool, *multi, uint64(*keyspacelen), samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed + int64(client_id))
+					go queryGoRoutimeZrevrangeByScore(clientConn, *multi, *keyspacestart, *keyspacelen, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), encodingThresholds, clientKeyConfig)
 				}
 			case "zrange-byscore":
 				if *clusterMode {
-					go queryGoRoutimeZrangeByScore(cluster, *multi, uint64(*keyspacelen), samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed + int64(client_id))
+					go queryGoRoutimeZrangeByScore(cluster, *multi, *keyspacestart, *keyspacelen, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), encodingThresholds, clientKeyConfig)
+				} else {
+					go queryGoRoutimeZrangeByScore(clientConn, *multi, *keyspacestart, *keyspacelen, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), encodingThresholds, clientKeyConfig)
+				}
+			case "zrange-withscores":
+				if *clusterMode {
+					go queryGoRoutimeZrangeWithScores(cluster, *multi, *keyspacestart, *keyspacelen, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), encodingThresholds, clientKeyConfig)
 				} else {
-					go queryGoRoutimeZrangeByScore(connectionPool, *multi, uint64(*keyspacelen), samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed + int64(client_id))
+					go queryGoRoutimeZrangeWithScores(clientConn, *multi, *keyspacestart, *keyspacelen, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), encodingThresholds, clientKeyConfig)
 				}
 			case "zrevrangebylex":
 				if *clusterMode {
-					go queryGoRoutimeZrangeByLex(cluster, *multi, uint64(*keyspacelen), samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed + int64(client_id))
+					go queryGoRoutimeZrangeByLex(cluster, *multi, *keyspacestart, *keyspacelen, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), encodingThresholds, clientKeyConfig)
+				} else {
+					go queryGoRoutimeZrangeByLex(clientConn, *multi, *keyspacestart, *keyspacelen, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), encodingThresholds, clientKeyConfig)
+				}
+			case "zscore":
+				if *clusterMode {
+					go queryGoRoutimeZscore(cluster, *multi, *keyspacestart, *keyspacelen, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), encodingThresholds, clientKeyConfig)
 				} else {
-					go queryGoRoutimeZrangeByLex(connectionPool, *multi, uint64(*keyspacelen), samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed + int64(client_id))
+					go queryGoRoutimeZscore(clientConn, *multi, *keyspacestart, *keyspacelen, samplesPerClient, *pipeline, *perKeyElmDataSize, *perKeyElmRangeStart, *perKeyElmRangeEnd, int(*debug), &wg, useRateLimiter, rateLimiter, *seed+int64(client_id), encodingThresholds, clientKeyConfig)
 				}
 			}
 		}
 	}
 
+	atomic.StoreInt32(&benchStarted, 1)
+
 	// listen for C-c
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 
 	tick := time.NewTicker(time.Duration(client_update_tick) * time.Second)
-	closed, _, duration, totalMessages, _ := updateCLI(tick, c, totalCmds)
+	closed, start, duration, totalMessages, _ := updateCLI(tick, c, totalCmds)
+	var serverStatsSummary []serverStatsResults = nil
+	if serverStats != nil {
+		serverStats.stop()
+		serverStatsSummary = serverStats.results()
+	}
+	clientUsageEnd := getClientUsage()
 	messageRate := float64(totalMessages) / float64(duration.Seconds())
 	p50IngestionMs := float64(latencies.ValueAtQuantile(50.0)) / 1000.0
 	p95IngestionMs := float64(latencies.ValueAtQuantile(95.0)) / 1000.0
@@ -166,14 +637,74 @@ func main() {
 	fmt.Printf("Total Duration %.3f Seconds\n", duration.Seconds())
 	fmt.Printf("Total Issued commands %d\n", totalMessages)
 	fmt.Printf("Total Errors %d\n", totalErrors)
+	fmt.Printf("Total Timeouts %d. Total Reconnects %d (%d failed)\n", totalTimeouts, totalReconnects, totalReconnectFailures)
 	fmt.Printf("Throughput summary: %.0f requests per second\n", messageRate)
 	if isLoad {
-		avgZcard := float64(totalAddedElements) / float64(totalCommands)
+		avgZcard := float64(totalAddedElements) / float64(totalCommands-totalErrors)
 		fmt.Printf("Average zcard %.0f elements\n", avgZcard)
 	}
 	fmt.Printf("Latency summary (msec):\n")
 	fmt.Printf("    %9s %9s %9s\n", "p50", "p95", "p99")
 	fmt.Printf("    %9.3f %9.3f %9.3f\n", p50IngestionMs, p95IngestionMs, p99IngestionMs)
+	printServerStatsSummary(serverStatsSummary)
+	if !isLoad && encodingThresholds != nil {
+		printEncodingLatencySummary()
+	}
+	nodeResults, slotRangeResults := getShardResults(duration)
+	var redirectResults *clusterRedirectResults = nil
+	if *clusterMode {
+		printShardSummary(nodeResults, slotRangeResults)
+		redirectResults = getClusterRedirectResults()
+		printClusterRedirectSummary(redirectResults, start, totalMessages)
+	}
+	var endpointResults []shardResults = nil
+	if endpointPools != nil {
+		endpointResults = getEndpointResults(duration)
+		printEndpointSummary(endpointResults)
+	}
+	var serverConns map[string]int
+	if *clusterMode {
+		serverConns = getServerConns(cluster, connectionStr)
+		printServerConnsSummary(*connModel, nodeConns*uint64(len(serverConns)), serverConns)
+	} else {
+		serverConns = getServerConns(connectionPool, connectionStr)
+		for i, replicaPool := range replicaPools {
+			for addr, count := range getServerConns(replicaPool, replicaAddrs[i]) {
+				serverConns[addr] = count
+			}
+		}
+		for i := 1; i < len(endpointPools); i++ {
+			for addr, count := range getServerConns(endpointPools[i], endpointAddrs[i]) {
+				serverConns[addr] = count
+			}
+		}
+		printServerConnsSummary(*connModel, expectedConns, serverConns)
+	}
+	var resp3Results *resp3ReplyResults = nil
+	if respProtocol == 3 {
+		resp3Results = getRESP3ReplyResults()
+		printRESP3ReplySummary(resp3Results)
+	}
+	var failoverResults *sentinelFailoverResults = nil
+	if sentinel != nil {
+		failoverResults = getSentinelFailoverResults(*sentinelPrimary)
+		printSentinelFailoverSummary(failoverResults, start)
+	}
+	var replicationLag []replicaLag = nil
+	if *readReplicas {
+		if *clusterMode {
+			replicationLag = getClusterReplicationLag(cluster)
+		} else {
+			replicationLag = getStandaloneReplicationLag(connectionPool, connectionStr, replicaPools, replicaAddrs)
+		}
+		printReplicationLagSummary(replicationLag)
+	}
+	var churnSummary *churnResults = nil
+	if isChurn {
+		churnSummary = getChurnResults(churn, duration)
+		printChurnSummary(churnSummary)
+	}
+	printClientUsage(clientUsageStart, clientUsageEnd, duration, totalMessages)
 	if !isLoad && *printReplyHistogram {
 		fmt.Printf("#################################################\n")
 		fmt.Printf("Printing reply histogram\n")
@@ -189,6 +720,60 @@ func main() {
 		fmt.Printf("Total processed replies %d\n", total_replies)
 	}
 
+	if isLoad && encodingThresholds != nil {
+		if *clusterMode {
+			verifyEncodings(cluster, encodingThresholds, *keyspacestart, *keyspacelen, *encodingSamples, *seed)
+		} else {
+			verifyEncodings(connectionPool, encodingThresholds, *keyspacestart, *keyspacelen, *encodingSamples, *seed)
+		}
+	}
+	var memoryFootprint *memoryFootprintResults = nil
+	if isLoad && *memorySamples > 0 {
+		memoryAfter := getMemoryState(preflightConn, preflightAddrs, preflightClients, *db)
+		memoryFootprint = getMemoryFootprint(preflightConn, memoryBefore, memoryAfter, atomic.LoadUint64(&totalAddedElements), atomic.LoadUint64(&totalAddedMemberBytes), *keyspacestart, *keyspacelen, *memorySamples, *memoryUsageSamples, *seed)
+		printMemoryFootprintSummary(memoryFootprint)
+	}
+
+	if *jsonOutputFile != "" {
+		results := &benchmarkResults{
+			GitSHA1:                git_sha,
+			GitDirty:               toolGitDirty(),
+			Mode:                   *benchMode,
+			Network:                network,
+			DB:                     *db,
+			Protocol:               respProtocol,
+			TLS:                    *useTLS,
+			ConnectionModel:        *connModel,
+			ServerConnections:      serverConns,
+			StartTime:              start.Unix(),
+			DurationSecs:           duration.Seconds(),
+			TotalCommands:          totalMessages,
+			TotalErrors:            totalErrors,
+			TotalTimeouts:          atomic.LoadUint64(&totalTimeouts),
+			TotalReconnects:        atomic.LoadUint64(&totalReconnects),
+			TotalReconnectFailures: atomic.LoadUint64(&totalReconnectFailures),
+			OpsPerSec:              messageRate,
+			LatencyMsec:            latencyQuantilesMsec(latencies),
+			ServerStats:            serverStatsSummary,
+			Servers:                serverEnvs,
+			PreflightWarnings:      preflightWarnings,
+			Nodes:                  nodeResults,
+			SlotRanges:             slotRangeResults,
+			Redirects:              redirectResults,
+			Endpoints:              endpointResults,
+			ReadFromReplica:        *readReplicas,
+			ReplicationLag:         replicationLag,
+			Failover:               failoverResults,
+			RESP3Replies:           resp3Results,
+			MemoryFootprint:        memoryFootprint,
+			Churn:                  churnSummary,
+		}
+		if !isLoad {
+			results.Query = *query
+		}
+		saveJsonResult(results, *jsonOutputFile)
+	}
+
 	if closed {
 		return
 	}
@@ -199,54 +784,48 @@ func main() {
 	wg.Wait()
 }
 
-func queryGoRoutimeZrangeByLex(conn radix.Client, multi bool, keyspace_len uint64, samplesPerClient uint64, pipeline uint64, perKeyElmDataSize uint64, perKeyElmRangeStart uint64, perKeyElmRangeEnd uint64, debug int, w *sync.WaitGroup, useRateLimiter bool, rateLimiter *rate.Limiter, seed int64) {
+func queryGoRoutimeZrangeByLex(conn radix.Client, multi bool, keyspace_start uint64, keyspace_len uint64, samplesPerClient uint64, pipeline uint64, perKeyElmDataSize uint64, perKeyElmRangeStart uint64, perKeyElmRangeEnd uint64, debug int, w *sync.WaitGroup, useRateLimiter bool, rateLimiter *rate.Limiter, seed int64, encodingThresholds *zsetEncodingThresholds, keyConfig keyGeneratorConfig) {
 	defer w.Done()
 
 	r := rand.New(rand.NewSource(seed))
+	keys := newKeyGenerator(keyConfig, r)
 
 	var i uint64 = 0
-	var multiIncr uint64 = 0
-	var multiPad uint64 = 0
-	if multi {
-		multiIncr += 2
-		multiPad += 1
-	}
-	cmds := make([]radix.CmdAction, pipeline+multiIncr)
+	cmds := make([]radix.CmdAction, pipeline)
 	cmdReplies := make([][]string, pipeline)
 	for i < samplesPerClient {
-		key_n := r.Int63n(int64(keyspace_len))
+		key_n := keys.next()
+		batch_key_n := key_n
 
 		if useRateLimiter {
 			r := rateLimiter.ReserveN(time.Now(), int(pipeline))
 			time.Sleep(r.Delay())
 		}
 		var j uint64 = 0
-		if multi {
-			cmds[0] = radix.Cmd(nil, "MULTI")
-			cmds[pipeline+multiPad] = radix.Cmd(&cmdReplies, "EXEC")
-		}
 		for j < pipeline {
-			keyname := getBenchKeyName(uint64(key_n))
+			keyname := getBenchKeyName(keyspace_start + key_n)
 			cmdArgs := []string{keyname, fmt.Sprintf("[%c", charset[r.Intn(len(charset))]), "-"}
-			cmds[j+multiPad] = radix.Cmd(nil, "ZREVRANGEBYLEX", cmdArgs...)
+			cmds[j] = radix.Cmd(&cmdReplies[j], "ZREVRANGEBYLEX", cmdArgs...)
 			j = j + 1
-			key_n = key_n + crc16_num_slots
-			if uint64(key_n) > keyspace_len {
-				key_n = key_n % crc16_num_slots
-			}
+			key_n = nextSameSlotKeyOffset(key_n, keyspace_len)
 		}
-		var err error
 		startT := time.Now()
-		err = conn.Do(radix.Pipeline(cmds...))
+		failed, err := doBenchPipeline(conn, cmds, multi)
 		endT := time.Now()
 		if err != nil {
-			log.Fatalf("Received an error with the following command(s): %v, error: %v", cmds, err)
+			recordCommandErrors(failed, cmds, err, debug)
+			atomic.AddUint64(&totalCommands, uint64(pipeline))
+			i = i + pipeline
+			continue
 		}
 		duration := endT.Sub(startT)
 		err = latencies.RecordValue(duration.Microseconds())
 		if err != nil {
 			log.Fatalf("Received an error while recording latencies: %v", err)
 		}
+		if encodingThresholds != nil {
+			recordEncodingLatency(encodingThresholds.expectedEncoding(keyspace_start+batch_key_n), duration.Microseconds())
+		}
 		for _, reply := range cmdReplies {
 			atomic.AddUint64(&replySizes[len(reply)], 1)
 		}
@@ -255,54 +834,48 @@ func queryGoRoutimeZrangeByLex(conn radix.Client, multi bool, keyspace_len uint6
 	}
 }
 
-func queryGoRoutimeZrangeByScore(conn radix.Client, multi bool, keyspace_len uint64, samplesPerClient uint64, pipeline uint64, perKeyElmDataSize uint64, perKeyElmRangeStart uint64, perKeyElmRangeEnd uint64, debug int, w *sync.WaitGroup, useRateLimiter bool, rateLimiter *rate.Limiter, seed int64) {
+func queryGoRoutimeZrangeByScore(conn radix.Client, multi bool, keyspace_start uint64, keyspace_len uint64, samplesPerClient uint64, pipeline uint64, perKeyElmDataSize uint64, perKeyElmRangeStart uint64, perKeyElmRangeEnd uint64, debug int, w *sync.WaitGroup, useRateLimiter bool, rateLimiter *rate.Limiter, seed int64, encodingThresholds *zsetEncodingThresholds, keyConfig keyGeneratorConfig) {
 	defer w.Done()
 
 	r := rand.New(rand.NewSource(seed))
+	keys := newKeyGenerator(keyConfig, r)
 
 	var i uint64 = 0
-	var multiIncr uint64 = 0
-	var multiPad uint64 = 0
-	if multi {
-		multiIncr += 2
-		multiPad += 1
-	}
-	cmds := make([]radix.CmdAction, pipeline+multiIncr)
+	cmds := make([]radix.CmdAction, pipeline)
 	cmdReplies := make([][]string, pipeline)
 	for i < samplesPerClient {
-		key_n := r.Int63n(int64(keyspace_len))
+		key_n := keys.next()
+		batch_key_n := key_n
 
 		if useRateLimiter {
 			r := rateLimiter.ReserveN(time.Now(), int(pipeline))
 			time.Sleep(r.Delay())
 		}
 		var j uint64 = 0
-		if multi {
-			cmds[0] = radix.Cmd(nil, "MULTI")
-			cmds[pipeline+multiPad] = radix.Cmd(&cmdReplies, "EXEC")
-		}
 		for j < pipeline {
-			keyname := getBenchKeyName(uint64(key_n))
+			keyname := getBenchKeyName(keyspace_start + key_n)
 			cmdArgs := []string{keyname, "0", "1", "BYSCORE"}
-			cmds[j+multiPad] = radix.Cmd(&cmdReplies[j], "ZRANGE", cmdArgs...)
+			cmds[j] = radix.Cmd(&cmdReplies[j], "ZRANGE", cmdArgs...)
 			j = j + 1
-			key_n = key_n + crc16_num_slots
-			if uint64(key_n) > keyspace_len {
-				key_n = key_n % crc16_num_slots
-			}
+			key_n = nextSameSlotKeyOffset(key_n, keyspace_len)
 		}
-		var err error
 		startT := time.Now()
-		err = conn.Do(radix.Pipeline(cmds...))
+		failed, err := doBenchPipeline(conn, cmds, multi)
 		endT := time.Now()
 		if err != nil {
-			log.Fatalf("Received an error with the following command(s): %v, error: %v", cmds, err)
+			recordCommandErrors(failed, cmds, err, debug)
+			atomic.AddUint64(&totalCommands, uint64(pipeline))
+			i = i + pipeline
+			continue
 		}
 		duration := endT.Sub(startT)
 		err = latencies.RecordValue(duration.Microseconds())
 		if err != nil {
 			log.Fatalf("Received an error while recording latencies: %v", err)
 		}
+		if encodingThresholds != nil {
+			recordEncodingLatency(encodingThresholds.expectedEncoding(keyspace_start+batch_key_n), duration.Microseconds())
+		}
 		for _, reply := range cmdReplies {
 			atomic.AddUint64(&replySizes[len(reply)/2], 1)
 		}
@@ -311,54 +884,109 @@ func queryGoRoutimeZrangeByScore(conn radix.Client, multi bool, keyspace_len uin
 	}
 }
 
-func queryGoRoutimeZrevrangeByScore(conn radix.Client, multi bool, keyspace_len uint64, samplesPerClient uint64, pipeline uint64, perKeyElmDataSize uint64, perKeyElmRangeStart uint64, perKeyElmRangeEnd uint64, debug int, w *sync.WaitGroup, useRateLimiter bool, rateLimiter *rate.Limiter, seed int64) {
+func queryGoRoutimeZrangeWithScores(conn radix.Client, multi bool, keyspace_start uint64, keyspace_len uint64, samplesPerClient uint64, pipeline uint64, perKeyElmDataSize uint64, perKeyElmRangeStart uint64, perKeyElmRangeEnd uint64, debug int, w *sync.WaitGroup, useRateLimiter bool, rateLimiter *rate.Limiter, seed int64, encodingThresholds *zsetEncodingThresholds, keyConfig keyGeneratorConfig) {
 	defer w.Done()
 
 	r := rand.New(rand.NewSource(seed))
+	keys := newKeyGenerator(keyConfig, r)
 
 	var i uint64 = 0
-	var multiIncr uint64 = 0
-	var multiPad uint64 = 0
-	if multi {
-		multiIncr += 2
-		multiPad += 1
+	cmds := make([]radix.CmdAction, pipeline)
+	cmdReplies := make([][]interface{}, pipeline)
+	for i < samplesPerClient {
+		key_n := keys.next()
+		batch_key_n := key_n
+
+		if useRateLimiter {
+			r := rateLimiter.ReserveN(time.Now(), int(pipeline))
+			time.Sleep(r.Delay())
+		}
+		var j uint64 = 0
+		for j < pipeline {
+			keyname := getBenchKeyName(keyspace_start + key_n)
+			cmdArgs := []string{keyname, "0", "1", "BYSCORE", "WITHSCORES"}
+			cmds[j] = radix.Cmd(&cmdReplies[j], "ZRANGE", cmdArgs...)
+			j = j + 1
+			key_n = nextSameSlotKeyOffset(key_n, keyspace_len)
+		}
+		startT := time.Now()
+		failed, err := doBenchPipeline(conn, cmds, multi)
+		endT := time.Now()
+		if err != nil {
+			recordCommandErrors(failed, cmds, err, debug)
+			atomic.AddUint64(&totalCommands, uint64(pipeline))
+			i = i + pipeline
+			continue
+		}
+		duration := endT.Sub(startT)
+		err = latencies.RecordValue(duration.Microseconds())
+		if err != nil {
+			log.Fatalf("Received an error while recording latencies: %v", err)
+		}
+		if encodingThresholds != nil {
+			recordEncodingLatency(encodingThresholds.expectedEncoding(keyspace_start+batch_key_n), duration.Microseconds())
+		}
+		for _, reply := range cmdReplies {
+			atomic.AddUint64(&replySizes[withScoresReplyLen(reply)], 1)
+		}
+		atomic.AddUint64(&totalCommands, uint64(pipeline))
+		i = i + pipeline
+	}
+}
+
+// withScoresReplyLen returns the number of members of a WITHSCORES reply, which is a flat
+// array of members and scores on RESP2, and an array of member and score pairs on RESP3.
+func withScoresReplyLen(reply []interface{}) int {
+	if len(reply) > 0 {
+		if _, isPair := reply[0].([]interface{}); isPair {
+			return len(reply)
+		}
 	}
-	cmds := make([]radix.CmdAction, pipeline+multiIncr)
+	return len(reply) / 2
+}
+
+func queryGoRoutimeZrevrangeByScore(conn radix.Client, multi bool, keyspace_start uint64, keyspace_len uint64, samplesPerClient uint64, pipeline uint64, perKeyElmDataSize uint64, perKeyElmRangeStart uint64, perKeyElmRangeEnd uint64, debug int, w *sync.WaitGroup, useRateLimiter bool, rateLimiter *rate.Limiter, seed int64, encodingThresholds *zsetEncodingThresholds, keyConfig keyGeneratorConfig) {
+	defer w.Done()
+
+	r := rand.New(rand.NewSource(seed))
+	keys := newKeyGenerator(keyConfig, r)
+
+	var i uint64 = 0
+	cmds := make([]radix.CmdAction, pipeline)
 	cmdReplies := make([][]string, pipeline)
 	for i < samplesPerClient {
-		key_n := r.Int63n(int64(keyspace_len))
+		key_n := keys.next()
+		batch_key_n := key_n
 
 		if useRateLimiter {
 			r := rateLimiter.ReserveN(time.Now(), int(pipeline))
 			time.Sleep(r.Delay())
 		}
 		var j uint64 = 0
-		if multi {
-			cmds[0] = radix.Cmd(nil, "MULTI")
-			cmds[pipeline+multiPad] = radix.Cmd(&cmdReplies, "EXEC")
-		}
 		for j < pipeline {
-			keyname := getBenchKeyName(uint64(key_n))
+			keyname := getBenchKeyName(keyspace_start + key_n)
 			cmdArgs := []string{keyname, "1", "0"}
-			cmds[j+multiPad] = radix.Cmd(&cmdReplies[j], "ZREVRANGEBYSCORE", cmdArgs...)
+			cmds[j] = radix.Cmd(&cmdReplies[j], "ZREVRANGEBYSCORE", cmdArgs...)
 			j = j + 1
-			key_n = key_n + crc16_num_slots
-			if uint64(key_n) > keyspace_len {
-				key_n = key_n % crc16_num_slots
-			}
+			key_n = nextSameSlotKeyOffset(key_n, keyspace_len)
 		}
-		var err error
 		startT := time.Now()
-		err = conn.Do(radix.Pipeline(cmds...))
+		failed, err := doBenchPipeline(conn, cmds, multi)
 		endT := time.Now()
 		if err != nil {
-			log.Fatalf("Received an error with the following command(s): %v, error: %v", cmds, err)
+			recordCommandErrors(failed, cmds, err, debug)
+			atomic.AddUint64(&totalCommands, uint64(pipeline))
+			i = i + pipeline
+			continue
 		}
 		duration := endT.Sub(startT)
 		err = latencies.RecordValue(duration.Microseconds())
 		if err != nil {
 			log.Fatalf("Received an error while recording latencies: %v", err)
 		}
+		if encodingThresholds != nil {
+			recordEncodingLatency(encodingThresholds.expectedEncoding(keyspace_start+batch_key_n), duration.Microseconds())
+		}
 		for _, reply := range cmdReplies {
 			atomic.AddUint64(&replySizes[len(reply)/2], 1)
 		}
@@ -367,52 +995,173 @@ func queryGoRoutimeZrevrangeByScore(conn radix.Client, multi bool, keyspace_len
 	}
 }
 
-func loadGoRoutime(conn radix.Client, keyspace_client_start uint64, keyspace_client_end uint64, samplesPerClient uint64, pipeline uint64, perKeyElmDataSize uint64, perKeyElmRangeStart uint64, perKeyElmRangeEnd uint64, debug int, w *sync.WaitGroup, useRateLimiter bool, rateLimiter *rate.Limiter, seed int64) {
+func queryGoRoutimeZscore(conn radix.Client, multi bool, keyspace_start uint64, keyspace_len uint64, samplesPerClient uint64, pipeline uint64, perKeyElmDataSize uint64, perKeyElmRangeStart uint64, perKeyElmRangeEnd uint64, debug int, w *sync.WaitGroup, useRateLimiter bool, rateLimiter *rate.Limiter, seed int64, encodingThresholds *zsetEncodingThresholds, keyConfig keyGeneratorConfig) {
+	defer w.Done()
+
+	r := rand.New(rand.NewSource(seed))
+	keys := newKeyGenerator(keyConfig, r)
+
+	var i uint64 = 0
+	cmds := make([]radix.CmdAction, pipeline)
+	lookups := make([]radix.CmdAction, pipeline)
+	keynames := make([]string, pipeline)
+	members := make([]string, pipeline)
+	cmdReplies := make([]radix.MaybeNil, pipeline)
+	for i < samplesPerClient {
+		key_n := keys.next()
+		batch_key_n := key_n
+
+		if useRateLimiter {
+			r := rateLimiter.ReserveN(time.Now(), int(pipeline))
+			time.Sleep(r.Delay())
+		}
+		var j uint64 = 0
+		for j < pipeline {
+			keynames[j] = getBenchKeyName(keyspace_start + key_n)
+			lookups[j] = radix.Cmd(&members[j], "ZRANDMEMBER", keynames[j])
+			j = j + 1
+			key_n = nextSameSlotKeyOffset(key_n, keyspace_len)
+		}
+		// the members are picked ahead of the timed pipeline, so that the queried scores exist
+		if err := doScoreMemberLookups(conn, lookups); err != nil {
+			recordCommandErrors(pipeline, lookups, err, debug)
+			atomic.AddUint64(&totalCommands, uint64(pipeline))
+			i = i + pipeline
+			continue
+		}
+		for j = 0; j < pipeline; j++ {
+			cmdReplies[j] = radix.MaybeNil{}
+			cmds[j] = radix.Cmd(&cmdReplies[j], "ZSCORE", keynames[j], members[j])
+		}
+		startT := time.Now()
+		failed, err := doBenchPipeline(conn, cmds, multi)
+		endT := time.Now()
+		if err != nil {
+			recordCommandErrors(failed, cmds, err, debug)
+			atomic.AddUint64(&totalCommands, uint64(pipeline))
+			i = i + pipeline
+			continue
+		}
+		duration := endT.Sub(startT)
+		err = latencies.RecordValue(duration.Microseconds())
+		if err != nil {
+			log.Fatalf("Received an error while recording latencies: %v", err)
+		}
+		if encodingThresholds != nil {
+			recordEncodingLatency(encodingThresholds.expectedEncoding(keyspace_start+batch_key_n), duration.Microseconds())
+		}
+		for _, reply := range cmdReplies {
+			if reply.Nil {
+				atomic.AddUint64(&replySizes[0], 1)
+			} else {
+				atomic.AddUint64(&replySizes[1], 1)
+			}
+		}
+		atomic.AddUint64(&totalCommands, uint64(pipeline))
+		i = i + pipeline
+	}
+}
+
+// doScoreMemberLookups issues the ZRANDMEMBER lookups of the zscore query. On cluster connections each lookup
+// is issued on its own, as the keys of a pipeline only share a node when their names carry a hash tag.
+func doScoreMemberLookups(conn radix.Client, lookups []radix.CmdAction) error {
+	if _, isCluster := conn.(*radix.Cluster); !isCluster {
+		return conn.Do(radix.Pipeline(lookups...))
+	}
+	for _, lookup := range lookups {
+		if err := conn.Do(lookup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadGoRoutime(conn radix.Client, keyspace_client_start uint64, keyspace_client_end uint64, samplesPerClient uint64, pipeline uint64, perKeyElmDataSize uint64, perKeyElmRangeStart uint64, perKeyElmRangeEnd uint64, debug int, w *sync.WaitGroup, useRateLimiter bool, rateLimiter *rate.Limiter, seed int64, encodingThresholds *zsetEncodingThresholds, scoreConfig scoreGeneratorConfig, memberConfig memberGeneratorConfig) {
 	defer w.Done()
 
 	r := rand.New(rand.NewSource(seed))
+	scores := newScoreGenerator(scoreConfig, r)
+	members := newMemberGenerator(memberConfig, r)
 
 	var i uint64 = 0
 	var keypos uint64 = keyspace_client_start
+	// the ZADD commands and the scratch buffer are reused across batches,
+	// so that generating the members does not allocate
+	zaddCmds := make([]*rawCmd, pipeline)
 	cmds := make([]radix.CmdAction, pipeline)
+	for j := range zaddCmds {
+		zaddCmds[j] = newRawCmd()
+		cmds[j] = zaddCmds[j]
+	}
+	scratch := make([]byte, 0, 1024)
 	for i < samplesPerClient {
 		if useRateLimiter {
 			r := rateLimiter.ReserveN(time.Now(), int(pipeline))
 			time.Sleep(r.Delay())
 		}
 		var j uint64 = 0
+		var batchElements, batchMemberBytes uint64 = 0, 0
 		for ; j < pipeline; j++ {
 			keyname := getBenchKeyName(keypos)
-			cmdArgs := []string{keyname}
+			zaddCmds[j].reset("ZADD", keyname)
 			nElements := r.Int63n(int64(perKeyElmRangeEnd-perKeyElmRangeStart)) + int64(perKeyElmRangeStart)
+			dataSize := perKeyElmDataSize
+			if encodingThresholds != nil {
+				nElements, dataSize = encodingThresholds.keyShape(keypos, perKeyElmDataSize)
+			}
 			var k int64 = 0
 			for ; k < nElements; k++ {
-				cmdArgs = append(cmdArgs, fmt.Sprintf("%f", r.Float32()), stringWithCharset(int(perKeyElmDataSize), charset, r))
+				memberSize := dataSize
+				if encodingThresholds == nil {
+					memberSize = members.nextSize()
+				}
+				scratch = scores.appendNext(scratch[:0])
+				zaddCmds[j].appendArgBytes(scratch)
+				scratch = members.appendNext(scratch[:0], memberSize)
+				zaddCmds[j].appendArgBytes(scratch)
+				batchMemberBytes += uint64(len(scratch))
 			}
-			atomic.AddUint64(&totalAddedElements, uint64(nElements))
-			cmds[j] = radix.Cmd(nil, "ZADD", cmdArgs...)
+			batchElements += uint64(nElements)
 			keypos++
 		}
-		var err error
 		startT := time.Now()
-		err = conn.Do(radix.Pipeline(cmds...))
+		failed, err := doBenchPipeline(conn, cmds, false)
 		endT := time.Now()
 		if err != nil {
-			log.Fatalf("Received an error with the following command(s): %v, error: %v", cmds, err)
+			recordCommandErrors(failed, cmds, err, debug)
+			atomic.AddUint64(&totalCommands, uint64(pipeline))
+			i = i + pipeline
+			continue
 		}
 		duration := endT.Sub(startT)
 		err = latencies.RecordValue(duration.Microseconds())
 		if err != nil {
 			log.Fatalf("Received an error while recording latencies: %v", err)
 		}
+		// only the elements of the successful pipelines are accounted as added
+		atomic.AddUint64(&totalAddedElements, batchElements)
+		atomic.AddUint64(&totalAddedMemberBytes, batchMemberBytes)
 		atomic.AddUint64(&totalCommands, uint64(pipeline))
 		i = i + pipeline
 	}
 }
 
-func getBenchKeyName(keypos uint64) string {
-	keyname := fmt.Sprintf("zbench:{%s}:%d", crc16_slot_table[keypos%crc16_num_slots], keypos)
-	return keyname
+// recordCommandErrors exits on the failed commands, unless continueOnError is set, in which case they are counted.
+// Only the first error is logged, unless debug is enabled, so that a failing server does not flood the output.
+func recordCommandErrors(failed uint64, cmds []radix.CmdAction, err error, debug int) {
+	if !continueOnError {
+		log.Fatalf("Received an error with the following command(s): %v, error: %v", cmds, err)
+	}
+	atomic.AddUint64(&totalErrors, failed)
+	recordTimeout(err)
+	logged := false
+	firstErrorOnce.Do(func() {
+		log.Printf("Received an error with the following command(s): %v, error: %v", cmds, err)
+		logged = true
+	})
+	if debug > 0 && !logged {
+		log.Printf("Received an error with the following command(s): %v, error: %v", cmds, err)
+	}
 }
 
 func updateCLI(tick *time.Ticker, c chan os.Signal, message_limit uint64) (bool, time.Time, time.Duration, uint64, []float64) {
@@ -421,7 +1170,7 @@ func updateCLI(tick *time.Ticker, c chan os.Signal, message_limit uint64) (bool,
 	prevTime := time.Now()
 	prevMessageCount := uint64(0)
 	messageRateTs := []float64{}
-	fmt.Printf("%26s %7s %25s %25s %7s %25s %25s\n", "Test time", " ", "Total Commands", "Total Errors", "", "Command Rate", "p50 lat. (msec)")
+	fmt.Printf("%26s %7s %25s %25s %7s %12s %12s %25s %25s\n", "Test time", " ", "Total Commands", "Total Errors", "", "Timeouts", "Reconnects", "Command Rate", "p50 lat. (msec)")
 	for {
 		select {
 		case <-tick.C:
@@ -444,7 +1193,7 @@ func updateCLI(tick *time.Ticker, c chan os.Signal, message_limit uint64) (bool,
 				prevMessageCount = totalCommands
 				prevTime = now
 
-				fmt.Printf("%25.0fs %s %25d %25d [%3.1f%%] %25.2f %25.2f\t", time.Since(start).Seconds(), completionPercentStr, totalCommands, totalErrors, errorPercent, messageRate, p50)
+				fmt.Printf("%25.0fs %s %25d %25d [%3.1f%%] %12d %12d %25.2f %25.2f\t", time.Since(start).Seconds(), completionPercentStr, totalCommands, totalErrors, errorPercent, atomic.LoadUint64(&totalTimeouts), atomic.LoadUint64(&totalReconnects), messageRate, p50)
 				fmt.Printf("\r")
 				if message_limit > 0 && totalCommands >= uint64(message_limit) {
 					return true, start, time.Since(start), totalCommands, messageRateTs
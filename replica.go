@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mediocregopher/radix/v3"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// readFromReplica routes the pipelines of the cluster connection to the replicas of the slot
+// primaries. It is only set on -mode=query.
+var readFromReplica = false
+
+// replicaPick rotates the replica each pipeline is issued to, when a primary has more than one replica.
+var replicaPick uint64
+
+// getClusterReplicas returns the replica addresses of each primary, as discovered via CLUSTER SLOTS.
+func getClusterReplicas(topo radix.ClusterTopo) map[string][]string {
+	replicas := map[string][]string{}
+	for _, node := range topo {
+		if node.SecondaryOfAddr != "" {
+			replicas[node.SecondaryOfAddr] = append(replicas[node.SecondaryOfAddr], node.Addr)
+		}
+	}
+	return replicas
+}
+
+// pickReplica returns the replica of the primary picked for the current pipeline,
+// falling back to the primary itself when it has no replicas.
+func pickReplica(replicas map[string][]string, primary string, pick uint64) string {
+	addrs := replicas[primary]
+	if len(addrs) == 0 {
+		return primary
+	}
+	return addrs[pick%uint64(len(addrs))]
+}
+
+func nextReplicaPick() uint64 {
+	return atomic.AddUint64(&replicaPick, 1)
+}
+
+// parseReplicaAddrs parses a comma separated list of host:port replica addresses.
+func parseReplicaAddrs(list string) ([]string, error) {
+	addrs := []string{}
+	for _, addr := range strings.Split(list, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if !strings.Contains(addr, ":") {
+			return nil, fmt.Errorf("invalid replica address %s. Expected host:port", addr)
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("empty replica address list")
+	}
+	return addrs, nil
+}
+
+// replicaLag is the replication state of a replica, as reported by INFO replication
+// on the replica and on its primary.
+type replicaLag struct {
+	Addr             string
+	Primary          string
+	LinkStatus       string
+	LastIOSecondsAgo int64
+	PrimaryOffset    int64
+	ReplicaOffset    int64
+	OffsetLag        int64
+}
+
+func getInfoReplication(client radix.Client) (map[string]string, error) {
+	return getInfo(client, "replication")
+}
+
+func getReplicaLag(primary radix.Client, primaryAddr string, replica radix.Client, replicaAddr string) (replicaLag, error) {
+	lag := replicaLag{Addr: replicaAddr, Primary: primaryAddr}
+	replicaInfo, err := getInfoReplication(replica)
+	if err != nil {
+		return lag, err
+	}
+	if replicaInfo["role"] != "slave" {
+		return lag, fmt.Errorf("%s is not a replica (role:%s)", replicaAddr, replicaInfo["role"])
+	}
+	primaryInfo, err := getInfoReplication(primary)
+	if err != nil {
+		return lag, err
+	}
+	lag.LinkStatus = replicaInfo["master_link_status"]
+	lag.LastIOSecondsAgo, _ = strconv.ParseInt(replicaInfo["master_last_io_seconds_ago"], 10, 64)
+	lag.ReplicaOffset, _ = strconv.ParseInt(replicaInfo["slave_repl_offset"], 10, 64)
+	lag.PrimaryOffset, _ = strconv.ParseInt(primaryInfo["master_repl_offset"], 10, 64)
+	lag.OffsetLag = lag.PrimaryOffset - lag.ReplicaOffset
+	return lag, nil
+}
+
+func getClusterReplicationLag(cluster *radix.Cluster) []replicaLag {
+	lags := []replicaLag{}
+	for primaryAddr, replicaAddrs := range getClusterReplicas(cluster.Topo()) {
+		primary, err := cluster.Client(primaryAddr)
+		if err != nil {
+			log.Printf("Unable to get a client for primary %s: %v", primaryAddr, err)
+			continue
+		}
+		for _, replicaAddr := range replicaAddrs {
+			replica, err := cluster.Client(replicaAddr)
+			if err != nil {
+				log.Printf("Unable to get a client for replica %s: %v", replicaAddr, err)
+				continue
+			}
+			lag, err := getReplicaLag(primary, primaryAddr, replica, replicaAddr)
+			if err != nil {
+				log.Printf("Unable to retrieve the replication state of %s: %v", replicaAddr, err)
+				continue
+			}
+			lags = append(lags, lag)
+		}
+	}
+	sort.Slice(lags, func(i, j int) bool { return lags[i].Addr < lags[j].Addr })
+	return lags
+}
+
+func getStandaloneReplicationLag(primary radix.Client, primaryAddr string, replicas []radix.Client, replicaAddrs []string) []replicaLag {
+	lags := []replicaLag{}
+	for i, replica := range replicas {
+		lag, err := getReplicaLag(primary, primaryAddr, replica, replicaAddrs[i])
+		if err != nil {
+			log.Printf("Unable to retrieve the replication state of %s: %v", replicaAddrs[i], err)
+			continue
+		}
+		lags = append(lags, lag)
+	}
+	return lags
+}
+
+func printReplicationLagSummary(lags []replicaLag) {
+	fmt.Printf("#################################################\n")
+	fmt.Printf("Replication lag at the end of the benchmark\n")
+	fmt.Printf("    %-25s %-25s %6s %12s %15s\n", "Replica", "Primary", "Link", "Last IO (s)", "Offset lag (B)")
+	for _, lag := range lags {
+		fmt.Printf("    %-25s %-25s %6s %12d %15d\n", lag.Addr, lag.Primary, lag.LinkStatus, lag.LastIOSecondsAgo, lag.OffsetLag)
+	}
+}
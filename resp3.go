@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/mediocregopher/radix/v3"
+	"github.com/mediocregopher/radix/v3/resp"
+	"github.com/mediocregopher/radix/v3/resp/resp2"
+	"io"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+// respProtocol is the protocol negotiated via HELLO on every benchmark connection.
+var respProtocol = 2
+
+var resp3TypeNames = map[byte]string{
+	'+': "simple string",
+	'-': "simple error",
+	':': "number",
+	'$': "blob string",
+	'*': "array",
+	'_': "null",
+	',': "double",
+	'#': "boolean",
+	'!': "blob error",
+	'=': "verbatim string",
+	'(': "big number",
+	'%': "map",
+	'~': "set",
+	'|': "attribute",
+	'>': "push",
+}
+
+var resp3ReplyTypes [256]uint64
+var resp3ReplyBytes uint64
+var resp3ReplyRESP2Bytes uint64
+
+// resp3Conn decodes the RESP3 replies of a connection, converting each of them to its RESP2
+// equivalent before handing it over to the radix commands, which only understand RESP2.
+// Maps are flattened into arrays, sets become arrays, doubles and big numbers become blob strings,
+// booleans become numbers, and verbatim strings lose their format prefix. Attributes and
+// out of band push messages are discarded.
+type resp3Conn struct {
+	radix.Conn
+	conv    resp3Converter
+	reader  bytes.Reader
+	br      *bufio.Reader
+	decoder resp3Decoder
+}
+
+func newRESP3Conn(conn radix.Conn) *resp3Conn {
+	c := &resp3Conn{Conn: conn}
+	c.br = bufio.NewReader(&c.reader)
+	c.decoder.conn = c
+	return c
+}
+
+// Do runs the action against the resp3Conn itself, so that every Decode goes through the conversion.
+func (c *resp3Conn) Do(a radix.Action) error {
+	return a.Run(c)
+}
+
+func (c *resp3Conn) Decode(u resp.Unmarshaler) error {
+	c.decoder.u = u
+	err := c.Conn.Decode(&c.decoder)
+	c.decoder.u = nil
+	return err
+}
+
+type resp3Decoder struct {
+	conn *resp3Conn
+	u    resp.Unmarshaler
+}
+
+func (d *resp3Decoder) UnmarshalRESP(br *bufio.Reader) error {
+	c := d.conn
+	for {
+		prefix, err := br.Peek(1)
+		if err != nil {
+			return err
+		}
+		c.conv.reset(br)
+		if err := c.conv.convert(); err != nil {
+			return err
+		}
+		if prefix[0] == '>' {
+			continue
+		}
+		atomic.AddUint64(&resp3ReplyTypes[prefix[0]], 1)
+		atomic.AddUint64(&resp3ReplyBytes, c.conv.read)
+		atomic.AddUint64(&resp3ReplyRESP2Bytes, uint64(len(c.conv.dst)))
+		break
+	}
+	c.reader.Reset(c.conv.dst)
+	c.br.Reset(&c.reader)
+	err := d.u.UnmarshalRESP(c.br)
+	// the whole reply was consumed off the wire by the conversion, so the connection remains usable
+	var respErr resp2.Error
+	var discarded resp.ErrDiscarded
+	if err != nil && !errors.As(err, &respErr) && !errors.As(err, &discarded) {
+		err = resp.ErrDiscarded{Err: err}
+	}
+	return err
+}
+
+// resp3Converter reads a single RESP3 value, appending its RESP2 encoding to dst.
+type resp3Converter struct {
+	br  *bufio.Reader
+	dst []byte
+	// number of bytes read off the wire
+	read uint64
+}
+
+func (c *resp3Converter) reset(br *bufio.Reader) {
+	c.br = br
+	c.dst = c.dst[:0]
+	c.read = 0
+}
+
+func (c *resp3Converter) readLine() ([]byte, error) {
+	line, err := c.br.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	c.read += uint64(len(line))
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("malformed RESP3 line %q", line)
+	}
+	return line, nil
+}
+
+// readBytes appends the next n bytes, plus the trailing CRLF, to dst.
+func (c *resp3Converter) readBytes(n int64) error {
+	var data []byte
+	c.dst, data = growBytes(c.dst, int(n)+2)
+	if _, err := io.ReadFull(c.br, data); err != nil {
+		return err
+	}
+	c.read += uint64(n) + 2
+	return nil
+}
+
+func (c *resp3Converter) appendHeader(prefix byte, n int64) {
+	c.dst = append(c.dst, prefix)
+	c.dst = strconv.AppendInt(c.dst, n, 10)
+	c.dst = append(c.dst, '\r', '\n')
+}
+
+func (c *resp3Converter) convert() error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	prefix, body := line[0], line[1:len(line)-2]
+	switch prefix {
+	case '+', '-', ':':
+		c.dst = append(c.dst, line...)
+	case '_':
+		c.dst = append(c.dst, "$-1\r\n"...)
+	case '#':
+		if len(body) == 1 && body[0] == 't' {
+			c.dst = append(c.dst, ":1\r\n"...)
+		} else {
+			c.dst = append(c.dst, ":0\r\n"...)
+		}
+	case ',', '(':
+		c.appendHeader('$', int64(len(body)))
+		c.dst = append(c.dst, body...)
+		c.dst = append(c.dst, '\r', '\n')
+	case '$', '!', '=':
+		n, err := parseRESPInt(body)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			c.dst = append(c.dst, "$-1\r\n"...)
+			return nil
+		}
+		start := len(c.dst)
+		c.appendHeader('$', n)
+		dataStart := len(c.dst)
+		if err := c.readBytes(n); err != nil {
+			return err
+		}
+		switch {
+		case prefix == '!':
+			// -<message>\r\n
+			data := append([]byte{}, c.dst[dataStart:]...)
+			c.dst = append(c.dst[:start], '-')
+			c.dst = append(c.dst, data...)
+		case prefix == '=' && n >= 4:
+			// drop the 3 characters format and the colon, e.g. txt:
+			data := append([]byte{}, c.dst[dataStart+4:]...)
+			c.dst = c.dst[:start]
+			c.appendHeader('$', n-4)
+			c.dst = append(c.dst, data...)
+		}
+	case '*', '~', '>':
+		n, err := parseRESPInt(body)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			c.dst = append(c.dst, "*-1\r\n"...)
+			return nil
+		}
+		c.appendHeader('*', n)
+		for i := int64(0); i < n; i++ {
+			if err := c.convert(); err != nil {
+				return err
+			}
+		}
+	case '%':
+		n, err := parseRESPInt(body)
+		if err != nil {
+			return err
+		}
+		c.appendHeader('*', 2*n)
+		for i := int64(0); i < 2*n; i++ {
+			if err := c.convert(); err != nil {
+				return err
+			}
+		}
+	case '|':
+		// attributes carry auxiliary data ahead of the actual value, which are discarded
+		n, err := parseRESPInt(body)
+		if err != nil {
+			return err
+		}
+		start := len(c.dst)
+		for i := int64(0); i < 2*n; i++ {
+			if err := c.convert(); err != nil {
+				return err
+			}
+		}
+		c.dst = c.dst[:start]
+		return c.convert()
+	default:
+		return fmt.Errorf("unsupported RESP3 type %q", prefix)
+	}
+	return nil
+}
+
+func parseRESPInt(b []byte) (int64, error) {
+	if len(b) == 0 {
+		return 0, fmt.Errorf("empty RESP3 length")
+	}
+	negative := b[0] == '-'
+	if negative {
+		b = b[1:]
+	}
+	var n int64 = 0
+	for _, ch := range b {
+		if ch < '0' || ch > '9' {
+			return 0, fmt.Errorf("invalid RESP3 length %q", b)
+		}
+		n = n*10 + int64(ch-'0')
+	}
+	if negative {
+		n = -n
+	}
+	return n, nil
+}
+
+// resp3ReplyResults is the RESP3 replies section of the benchmark results.
+type resp3ReplyResults struct {
+	Replies          map[string]uint64
+	ReplyBytes       uint64
+	RESP2ReplyBytes  uint64
+	ReplyBytesChange float64
+}
+
+func getRESP3ReplyResults() *resp3ReplyResults {
+	results := &resp3ReplyResults{
+		Replies:         map[string]uint64{},
+		ReplyBytes:      atomic.LoadUint64(&resp3ReplyBytes),
+		RESP2ReplyBytes: atomic.LoadUint64(&resp3ReplyRESP2Bytes),
+	}
+	for prefix, name := range resp3TypeNames {
+		if count := atomic.LoadUint64(&resp3ReplyTypes[prefix]); count > 0 {
+			results.Replies[name] = count
+		}
+	}
+	if results.RESP2ReplyBytes > 0 {
+		results.ReplyBytesChange = (float64(results.ReplyBytes)/float64(results.RESP2ReplyBytes) - 1.0) * 100.0
+	}
+	return results
+}
+
+func printRESP3ReplySummary(results *resp3ReplyResults) {
+	fmt.Printf("#################################################\n")
+	fmt.Printf("RESP3 replies\n")
+	fmt.Printf("Reply bytes %d. RESP2 equivalent reply bytes %d (%+.2f%%)\n", results.ReplyBytes, results.RESP2ReplyBytes, results.ReplyBytesChange)
+	names := make([]string, 0, len(results.Replies))
+	for name := range results.Replies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("    %-16s %d\n", name, results.Replies[name])
+	}
+}
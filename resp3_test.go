@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestRESP3ConverterConvert(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    string
+		wantErr bool
+	}{
+		{"simple string", "+OK\r\n", "+OK\r\n", false},
+		{"simple error", "-ERR oops\r\n", "-ERR oops\r\n", false},
+		{"number", ":42\r\n", ":42\r\n", false},
+		{"null", "_\r\n", "$-1\r\n", false},
+		{"true", "#t\r\n", ":1\r\n", false},
+		{"false", "#f\r\n", ":0\r\n", false},
+		{"double", ",1.5\r\n", "$3\r\n1.5\r\n", false},
+		{"infinite double", ",inf\r\n", "$3\r\ninf\r\n", false},
+		{"big number", "(3492890328409238509324850943850943825024385\r\n", "$43\r\n3492890328409238509324850943850943825024385\r\n", false},
+		{"blob string", "$5\r\nhello\r\n", "$5\r\nhello\r\n", false},
+		{"nil blob string", "$-1\r\n", "$-1\r\n", false},
+		{"blob error", "!21\r\nSYNTAX invalid syntax\r\n", "-SYNTAX invalid syntax\r\n", false},
+		{"verbatim string", "=15\r\ntxt:Some string\r\n", "$11\r\nSome string\r\n", false},
+		{"array", "*2\r\n:1\r\n$1\r\na\r\n", "*2\r\n:1\r\n$1\r\na\r\n", false},
+		{"nil array", "*-1\r\n", "*-1\r\n", false},
+		{"set", "~2\r\n+a\r\n+b\r\n", "*2\r\n+a\r\n+b\r\n", false},
+		{"map flattened", "%2\r\n+a\r\n:1\r\n+b\r\n,2.5\r\n", "*4\r\n+a\r\n:1\r\n+b\r\n$3\r\n2.5\r\n", false},
+		{"zrange withscores", "*2\r\n*2\r\n$1\r\nm\r\n,0.5\r\n*2\r\n$1\r\nn\r\n,1\r\n", "*2\r\n*2\r\n$1\r\nm\r\n$3\r\n0.5\r\n*2\r\n$1\r\nn\r\n$1\r\n1\r\n", false},
+		{"attribute discarded", "|1\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.19\r\n*1\r\n:1\r\n", "*1\r\n:1\r\n", false},
+		{"unsupported type", "@1\r\n", "", true},
+		{"malformed line", "+OK\n", "", true},
+		{"invalid length", "*x\r\n", "", true},
+		{"truncated blob string", "$5\r\nhel", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c resp3Converter
+			c.reset(bufio.NewReader(strings.NewReader(tt.reply)))
+			err := c.convert()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("convert() of %q error = %v, wantErr %v", tt.reply, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := string(c.dst); got != tt.want {
+				t.Errorf("convert() of %q = %q, want %q", tt.reply, got, tt.want)
+			}
+			if c.read != uint64(len(tt.reply)) {
+				t.Errorf("convert() of %q read %d bytes, want %d", tt.reply, c.read, len(tt.reply))
+			}
+		})
+	}
+}
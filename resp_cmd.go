@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/mediocregopher/radix/v3"
+	"github.com/mediocregopher/radix/v3/resp/resp2"
+	"io"
+	"strconv"
+)
+
+// rawCmd is a radix.CmdAction whose RESP encoding is built in place on a reusable buffer.
+// Contrary to radix.Cmd, appending arguments does not require a string allocation per argument,
+// so a rawCmd can be reset and reused for every command issued by a client.
+// The reply is discarded.
+type rawCmd struct {
+	name   string
+	keys   [1]string
+	nArgs  int
+	body   []byte
+	header []byte
+}
+
+func newRawCmd() *rawCmd {
+	return &rawCmd{body: make([]byte, 0, 4096), header: make([]byte, 0, 32)}
+}
+
+// reset clears the previous arguments, and starts a new command for the given key.
+func (c *rawCmd) reset(name string, key string) {
+	c.name = name
+	c.keys[0] = key
+	c.nArgs = 0
+	c.body = c.body[:0]
+	c.appendArg(name)
+	c.appendArg(key)
+}
+
+func (c *rawCmd) appendArg(arg string) {
+	c.body = append(c.body, '$')
+	c.body = strconv.AppendInt(c.body, int64(len(arg)), 10)
+	c.body = append(c.body, '\r', '\n')
+	c.body = append(c.body, arg...)
+	c.body = append(c.body, '\r', '\n')
+	c.nArgs++
+}
+
+func (c *rawCmd) appendArgBytes(arg []byte) {
+	c.body = append(c.body, '$')
+	c.body = strconv.AppendInt(c.body, int64(len(arg)), 10)
+	c.body = append(c.body, '\r', '\n')
+	c.body = append(c.body, arg...)
+	c.body = append(c.body, '\r', '\n')
+	c.nArgs++
+}
+
+func (c *rawCmd) Keys() []string {
+	return c.keys[:]
+}
+
+func (c *rawCmd) Run(conn radix.Conn) error {
+	if err := conn.Encode(c); err != nil {
+		return err
+	}
+	return conn.Decode(c)
+}
+
+func (c *rawCmd) MarshalRESP(w io.Writer) error {
+	c.header = append(c.header[:0], '*')
+	c.header = strconv.AppendInt(c.header, int64(c.nArgs), 10)
+	c.header = append(c.header, '\r', '\n')
+	if _, err := w.Write(c.header); err != nil {
+		return err
+	}
+	_, err := w.Write(c.body)
+	return err
+}
+
+func (c *rawCmd) UnmarshalRESP(br *bufio.Reader) error {
+	return resp2.Any{}.UnmarshalRESP(br)
+}
+
+// ClusterCanRetry allows radix to follow MOVED and ASK redirects for this command.
+func (c *rawCmd) ClusterCanRetry() bool {
+	return true
+}
+
+func (c *rawCmd) String() string {
+	return fmt.Sprintf("%s %s (%d args)", c.name, c.keys[0], c.nArgs)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"github.com/mediocregopher/radix/v3"
+	"reflect"
+	"testing"
+)
+
+func TestRawCmdMarshalRESP(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		args []string
+	}{
+		{"no args", "zset:1", nil},
+		{"ZADD", "zset:1", []string{"1.5", "member-a", "-3", "member-b"}},
+		{"empty arg", "zset:1", []string{"", "member"}},
+		{"binary arg", "zset:1", []string{"1", "\x00\r\n\xff"}},
+		{"long arg", "zset:1", []string{string(bytes.Repeat([]byte("x"), 5000))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := bytes.Buffer{}
+			if err := radix.Cmd(nil, "ZADD", append([]string{tt.key}, tt.args...)...).MarshalRESP(&want); err != nil {
+				t.Fatalf("radix.Cmd MarshalRESP() error = %v", err)
+			}
+			cmd := newRawCmd()
+			// a reused command does not keep the arguments of the previous one
+			cmd.reset("ZADD", "previous")
+			cmd.appendArg("previous")
+			cmd.reset("ZADD", tt.key)
+			for i, arg := range tt.args {
+				if i%2 == 0 {
+					cmd.appendArg(arg)
+				} else {
+					cmd.appendArgBytes([]byte(arg))
+				}
+			}
+			got := bytes.Buffer{}
+			if err := cmd.MarshalRESP(&got); err != nil {
+				t.Fatalf("MarshalRESP() error = %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Errorf("MarshalRESP() = %q, want %q", got.Bytes(), want.Bytes())
+			}
+			if keys := cmd.Keys(); !reflect.DeepEqual(keys, []string{tt.key}) {
+				t.Errorf("Keys() = %v, want [%s]", keys, tt.key)
+			}
+		})
+	}
+}
+
+func TestRawCmdAllocs(t *testing.T) {
+	cmd := newRawCmd()
+	score := []byte("1.5")
+	member := []byte("member-of-twenty-chr")
+	discard := bytes.Buffer{}
+	run := func() {
+		cmd.reset("ZADD", "zset:1")
+		for i := 0; i < 100; i++ {
+			cmd.appendArgBytes(score)
+			cmd.appendArgBytes(member)
+		}
+		discard.Reset()
+		cmd.MarshalRESP(&discard)
+	}
+	// grow the buffers before measuring
+	run()
+	if allocs := testing.AllocsPerRun(100, run); allocs != 0 {
+		t.Errorf("reset, appendArgBytes and MarshalRESP allocated %.1f times per command, want 0", allocs)
+	}
+}
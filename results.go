@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+)
+
+// benchmarkResults is the JSON document written to -json-out-file.
+type benchmarkResults struct {
+	GitSHA1       string
+	GitDirty      bool
+	Mode          string
+	Query         string `json:",omitempty"`
+	Network       string
+	DB            int `json:",omitempty"`
+	Protocol      int
+	TLS           bool
+	StartTime     int64
+	DurationSecs  float64
+	TotalCommands uint64
+	TotalErrors   uint64
+	OpsPerSec     float64
+	LatencyMsec   map[string]float64
+
+	// server side deltas of the INFO samples taken during the run
+	ServerStats []serverStatsResults `json:",omitempty"`
+
+	// server version and configs captured by the preflight checks, along with the warnings they raised
+	Servers           []serverEnv
+	PreflightWarnings []string `json:",omitempty"`
+
+	// timed out commands and connects, and connections re-established after being dropped
+	TotalTimeouts          uint64
+	TotalReconnects        uint64
+	TotalReconnectFailures uint64
+
+	// benchmark connections per node, as reported by CLIENT LIST
+	ConnectionModel   string
+	ServerConnections map[string]int
+
+	Nodes      []shardResults          `json:",omitempty"`
+	SlotRanges []shardResults          `json:",omitempty"`
+	Redirects  *clusterRedirectResults `json:",omitempty"`
+
+	// set on -hosts outside -oss-cluster
+	Endpoints []shardResults `json:",omitempty"`
+
+	// set on -read-from-replica, along with the replication lag at the end of the benchmark
+	ReadFromReplica bool         `json:",omitempty"`
+	ReplicationLag  []replicaLag `json:",omitempty"`
+
+	// set on -sentinels
+	Failover *sentinelFailoverResults `json:",omitempty"`
+
+	// set on -resp 3
+	RESP3Replies *resp3ReplyResults `json:",omitempty"`
+
+	// set on -mode=load, unless -memory-samples is 0
+	MemoryFootprint *memoryFootprintResults `json:",omitempty"`
+
+	// set on -mode=churn
+	Churn *churnResults `json:",omitempty"`
+}
+
+func saveJsonResult(results *benchmarkResults, jsonOutputFile string) {
+	file, err := json.MarshalIndent(results, "", " ")
+	if err != nil {
+		log.Fatalf("Error while encoding the json results: %v", err)
+	}
+	err = ioutil.WriteFile(jsonOutputFile, file, 0644)
+	if err != nil {
+		log.Fatalf("Error while saving the json results to %s: %v", jsonOutputFile, err)
+	}
+	log.Printf("Saved json results to %s", jsonOutputFile)
+}
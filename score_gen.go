@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+const scoreDistributionFloat = "float"
+const scoreDistributionInteger = "integer"
+const scoreDistributionTimestamp = "timestamp"
+const scoreDistributionTimestampJitter = "timestamp-jitter"
+const scoreDistributionLargeDouble = "large-double"
+const scoreDistributionTies = "ties"
+const scoreDistributionInf = "inf"
+
+var scoreDistributions = []string{
+	scoreDistributionFloat,
+	scoreDistributionInteger,
+	scoreDistributionTimestamp,
+	scoreDistributionTimestampJitter,
+	scoreDistributionLargeDouble,
+	scoreDistributionTies,
+	scoreDistributionInf,
+}
+
+// largest integer that a double can represent exactly
+const maxExactIntegerScore = 1 << 53
+
+type scoreGeneratorConfig struct {
+	distribution string
+	// number of distinct scores used by the ties distribution
+	tiesCardinality int64
+	// max jitter in seconds, applied in both directions, for the timestamp-jitter distribution
+	timestampJitter int64
+	// percentage of +inf and -inf scores for the inf distribution
+	infPercent float64
+	// unix timestamp used as the base of the timestamp distributions
+	timestampBase int64
+}
+
+func isValidScoreDistribution(distribution string) bool {
+	return stringInSlice(distribution, scoreDistributions)
+}
+
+// scoreGenerator produces the scores for the loaded members.
+// Each load goroutine owns its generator, so it is not safe for concurrent use.
+type scoreGenerator struct {
+	config scoreGeneratorConfig
+	r      *rand.Rand
+	seq    int64
+}
+
+func newScoreGenerator(config scoreGeneratorConfig, r *rand.Rand) *scoreGenerator {
+	if config.timestampBase == 0 {
+		config.timestampBase = time.Now().Unix()
+	}
+	if config.tiesCardinality < 1 {
+		config.tiesCardinality = 1
+	}
+	return &scoreGenerator{config: config, r: r}
+}
+
+// appendNext appends the next score to dst, and returns the extended buffer.
+func (g *scoreGenerator) appendNext(dst []byte) []byte {
+	switch g.config.distribution {
+	case scoreDistributionInteger:
+		return strconv.AppendInt(dst, g.r.Int63n(maxExactIntegerScore), 10)
+	case scoreDistributionTimestamp:
+		g.seq++
+		return strconv.AppendInt(dst, g.config.timestampBase+g.seq, 10)
+	case scoreDistributionTimestampJitter:
+		g.seq++
+		jitter := int64(0)
+		if g.config.timestampJitter > 0 {
+			jitter = g.r.Int63n(2*g.config.timestampJitter+1) - g.config.timestampJitter
+		}
+		return strconv.AppendInt(dst, g.config.timestampBase+g.seq+jitter, 10)
+	case scoreDistributionLargeDouble:
+		// log-uniform magnitude in [1e15, 1e308), with random sign
+		score := math.Pow(10, 15+g.r.Float64()*293)
+		if g.r.Intn(2) == 0 {
+			score = -score
+		}
+		return strconv.AppendFloat(dst, score, 'g', 17, 64)
+	case scoreDistributionTies:
+		return strconv.AppendInt(dst, g.r.Int63n(g.config.tiesCardinality), 10)
+	case scoreDistributionInf:
+		p := g.r.Float64() * 100.0
+		if p < g.config.infPercent/2.0 {
+			return append(dst, "+inf"...)
+		} else if p < g.config.infPercent {
+			return append(dst, "-inf"...)
+		}
+		return appendFloat32Score(dst, g.r.Float32())
+	default:
+		return appendFloat32Score(dst, g.r.Float32())
+	}
+}
+
+// appendFloat32Score formats the score exactly as fmt's %f verb does for a float32.
+func appendFloat32Score(dst []byte, score float32) []byte {
+	return strconv.AppendFloat(dst, float64(score), 'f', 6, 32)
+}
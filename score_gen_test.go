@@ -0,0 +1,100 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestScoreGenerator(t *testing.T) {
+	const base = 1700000000
+	tests := []struct {
+		name   string
+		config scoreGeneratorConfig
+		// check validates the i-th generated score
+		check func(i int, score string, value float64) bool
+	}{
+		{"float", scoreGeneratorConfig{distribution: scoreDistributionFloat}, func(i int, score string, value float64) bool {
+			return value >= 0 && value < 1 && len(score) == len("0.000000")
+		}},
+		{"integer", scoreGeneratorConfig{distribution: scoreDistributionInteger}, func(i int, score string, value float64) bool {
+			return value >= 0 && value < maxExactIntegerScore && value == math.Trunc(value)
+		}},
+		{"timestamp", scoreGeneratorConfig{distribution: scoreDistributionTimestamp, timestampBase: base}, func(i int, score string, value float64) bool {
+			return score == strconv.Itoa(base+i+1)
+		}},
+		{"timestamp-jitter", scoreGeneratorConfig{distribution: scoreDistributionTimestampJitter, timestampBase: base, timestampJitter: 5}, func(i int, score string, value float64) bool {
+			return math.Abs(value-float64(base+i+1)) <= 5
+		}},
+		{"timestamp-jitter without jitter", scoreGeneratorConfig{distribution: scoreDistributionTimestampJitter, timestampBase: base}, func(i int, score string, value float64) bool {
+			return score == strconv.Itoa(base+i+1)
+		}},
+		{"large-double", scoreGeneratorConfig{distribution: scoreDistributionLargeDouble}, func(i int, score string, value float64) bool {
+			return math.Abs(value) >= 1e15 && !math.IsInf(value, 0)
+		}},
+		{"ties", scoreGeneratorConfig{distribution: scoreDistributionTies, tiesCardinality: 3}, func(i int, score string, value float64) bool {
+			return score == "0" || score == "1" || score == "2"
+		}},
+		{"ties without cardinality", scoreGeneratorConfig{distribution: scoreDistributionTies}, func(i int, score string, value float64) bool {
+			return score == "0"
+		}},
+		{"inf only", scoreGeneratorConfig{distribution: scoreDistributionInf, infPercent: 100}, func(i int, score string, value float64) bool {
+			return score == "+inf" || score == "-inf"
+		}},
+		{"inf disabled", scoreGeneratorConfig{distribution: scoreDistributionInf}, func(i int, score string, value float64) bool {
+			return value >= 0 && value < 1
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !isValidScoreDistribution(tt.config.distribution) {
+				t.Fatalf("isValidScoreDistribution(%s) = false", tt.config.distribution)
+			}
+			g := newScoreGenerator(tt.config, rand.New(rand.NewSource(12345)))
+			for i := 0; i < 1000; i++ {
+				score := string(g.appendNext(nil))
+				value, err := strconv.ParseFloat(score, 64)
+				if err != nil {
+					t.Fatalf("score #%d %q is not a valid ZADD score: %v", i, score, err)
+				}
+				if !tt.check(i, score, value) {
+					t.Fatalf("unexpected score #%d %q", i, score)
+				}
+			}
+		})
+	}
+}
+
+func TestScoreGeneratorInfShare(t *testing.T) {
+	g := newScoreGenerator(scoreGeneratorConfig{distribution: scoreDistributionInf, infPercent: 20}, rand.New(rand.NewSource(12345)))
+	positive, negative := 0, 0
+	for i := 0; i < 100000; i++ {
+		switch string(g.appendNext(nil)) {
+		case "+inf":
+			positive++
+		case "-inf":
+			negative++
+		}
+	}
+	if positive < 9500 || positive > 10500 || negative < 9500 || negative > 10500 {
+		t.Errorf("+inf %d and -inf %d scores out of 100000, want about 10000 each", positive, negative)
+	}
+}
+
+func TestAppendFloat32Score(t *testing.T) {
+	tests := []struct {
+		score float32
+		want  string
+	}{
+		{0, "0.000000"},
+		{0.5, "0.500000"},
+		{0.1234567, "0.123457"},
+		{0.99999994, "1.000000"},
+	}
+	for _, tt := range tests {
+		if got := string(appendFloat32Score(nil, tt.score)); got != tt.want {
+			t.Errorf("appendFloat32Score(%v) = %q, want %q", tt.score, got, tt.want)
+		}
+	}
+}
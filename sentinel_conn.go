@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mediocregopher/radix/v3"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// interval between checks of the primary advertised by the sentinels
+const sentinelPrimaryCheckInterval = 100 * time.Millisecond
+
+func getSentinelConn(sentinelAddrs []string, primaryName string, opts []radix.DialOpt, sentinelOpts []radix.DialOpt, clients uint64) *radix.Sentinel {
+	customConnFunc := func(network, addr string) (radix.Conn, error) {
+		return dialBenchConnWithBackoff(network, addr, opts)
+	}
+	sentinelConnFunc := func(network, addr string) (radix.Conn, error) {
+		return radix.Dial(network, addr, sentinelOpts...,
+		)
+	}
+	// the sentinel uses this pool func to create a pool to the current primary, and to the replicas
+	poolFunc := func(network, addr string) (radix.Client, error) {
+		return radix.NewPool(network, addr, int(clients), radix.PoolConnFunc(customConnFunc), radix.PoolPipelineWindow(0, 0))
+	}
+	sentinel, err := radix.NewSentinel(primaryName, sentinelAddrs, radix.SentinelConnFunc(sentinelConnFunc), radix.SentinelPoolFunc(poolFunc))
+	if err != nil {
+		log.Fatalf("Error preparing for benchmark, while connecting to the sentinels %v for primary %s. error = %v", sentinelAddrs, primaryName, err)
+	}
+	return sentinel
+}
+
+// parseSentinelAddrs parses a comma separated list of host:port sentinel addresses.
+func parseSentinelAddrs(list string) ([]string, error) {
+	addrs := []string{}
+	for _, addr := range strings.Split(list, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if !strings.Contains(addr, ":") {
+			return nil, fmt.Errorf("invalid sentinel address %s. Expected host:port", addr)
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("empty sentinel address list")
+	}
+	return addrs, nil
+}
+
+// primarySwitch is a change of the primary advertised by the sentinels.
+type primarySwitch struct {
+	Time time.Time
+	From string
+	To   string
+}
+
+// unavailabilityWindow spans from the first failed pipeline up to the next successful one.
+type unavailabilityWindow struct {
+	Start          time.Time
+	End            time.Time
+	DurationSecs   float64
+	FailedCommands uint64
+}
+
+var primarySwitches []primarySwitch
+var unavailabilityWindows []unavailabilityWindow
+
+// set while the primary is unavailable, to keep the successful pipelines lock free
+var primaryUnavailable int32
+var currentUnavailability unavailabilityWindow
+var availabilityMutex sync.Mutex
+
+// recordAvailability tracks the unavailability windows out of the outcome of each pipeline.
+func recordAvailability(failed uint64, err error) {
+	if err == nil && atomic.LoadInt32(&primaryUnavailable) == 0 {
+		return
+	}
+	availabilityMutex.Lock()
+	defer availabilityMutex.Unlock()
+	now := time.Now()
+	if err != nil {
+		if atomic.LoadInt32(&primaryUnavailable) == 0 {
+			currentUnavailability = unavailabilityWindow{Start: now}
+			atomic.StoreInt32(&primaryUnavailable, 1)
+		}
+		currentUnavailability.FailedCommands += failed
+		return
+	}
+	if atomic.LoadInt32(&primaryUnavailable) == 1 {
+		currentUnavailability.End = now
+		currentUnavailability.DurationSecs = now.Sub(currentUnavailability.Start).Seconds()
+		unavailabilityWindows = append(unavailabilityWindows, currentUnavailability)
+		atomic.StoreInt32(&primaryUnavailable, 0)
+	}
+}
+
+// watchSentinelPrimary records the primary switches until stop is closed.
+func watchSentinelPrimary(sentinel *radix.Sentinel, stop chan struct{}) {
+	current, _ := sentinel.Addrs()
+	tick := time.NewTicker(sentinelPrimaryCheckInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			primary, _ := sentinel.Addrs()
+			if primary != current {
+				availabilityMutex.Lock()
+				primarySwitches = append(primarySwitches, primarySwitch{Time: time.Now(), From: current, To: primary})
+				availabilityMutex.Unlock()
+				current = primary
+			}
+		case err := <-sentinel.ErrCh:
+			log.Printf("Received an error from the sentinel: %v", err)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// sentinelFailoverResults is the failover section of the benchmark results.
+type sentinelFailoverResults struct {
+	PrimaryName           string
+	PrimarySwitches       []primarySwitch
+	UnavailabilityWindows []unavailabilityWindow
+	UnavailableSecs       float64
+	FailedCommands        uint64
+}
+
+func getSentinelFailoverResults(primaryName string) *sentinelFailoverResults {
+	availabilityMutex.Lock()
+	defer availabilityMutex.Unlock()
+	windows := append([]unavailabilityWindow{}, unavailabilityWindows...)
+	// an unavailability window still open at the end of the benchmark is closed at that moment
+	if atomic.LoadInt32(&primaryUnavailable) == 1 {
+		open := currentUnavailability
+		open.End = time.Now()
+		open.DurationSecs = open.End.Sub(open.Start).Seconds()
+		windows = append(windows, open)
+	}
+	results := &sentinelFailoverResults{
+		PrimaryName:           primaryName,
+		PrimarySwitches:       append([]primarySwitch{}, primarySwitches...),
+		UnavailabilityWindows: windows,
+	}
+	for _, window := range windows {
+		results.UnavailableSecs += window.DurationSecs
+		results.FailedCommands += window.FailedCommands
+	}
+	return results
+}
+
+func printSentinelFailoverSummary(results *sentinelFailoverResults, start time.Time) {
+	fmt.Printf("#################################################\n")
+	fmt.Printf("Sentinel failovers of primary %s\n", results.PrimaryName)
+	fmt.Printf("Primary switches %d. Unavailability windows %d. Total unavailable %.3f seconds. Failed commands %d\n", len(results.PrimarySwitches), len(results.UnavailabilityWindows), results.UnavailableSecs, results.FailedCommands)
+	for _, primarySwitch := range results.PrimarySwitches {
+		fmt.Printf("%s (%+.3f sec) primary switched from %s to %s\n", primarySwitch.Time.Format(time.RFC3339), primarySwitch.Time.Sub(start).Seconds(), primarySwitch.From, primarySwitch.To)
+	}
+	for _, window := range results.UnavailabilityWindows {
+		fmt.Printf("%s (%+.3f sec) unavailable for %.3f seconds. Failed commands %d\n", window.Start.Format(time.RFC3339), window.Start.Sub(start).Seconds(), window.DurationSecs, window.FailedCommands)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mediocregopher/radix/v3"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// INFO sections sampled during the run. latencystats is only available since Redis 7.0,
+// and older servers reply with an empty section.
+var serverStatsSections = []string{"stats", "cpu", "memory", "commandstats", "latencystats"}
+
+const cmdstatPrefix = "cmdstat_"
+const latencyPercentilesPrefix = "latency_percentiles_usec_"
+
+// the INFO commands issued by the sampler itself are left out of the server side ops
+const cmdstatInfo = cmdstatPrefix + "info"
+
+// serverSample is the INFO of a node at a point in time.
+type serverSample struct {
+	time time.Time
+	info map[string]string
+}
+
+func (s serverSample) float(name string) float64 {
+	value, _ := strconv.ParseFloat(s.info[name], 64)
+	return value
+}
+
+// ops returns the commands processed by the server, besides INFO.
+func (s serverSample) ops() float64 {
+	infoCalls, _ := strconv.ParseFloat(parseInfoFields(s.info[cmdstatInfo])["calls"], 64)
+	return s.float("total_commands_processed") - infoCalls
+}
+
+// serverStatsSampler samples INFO on every node on an interval, from the benchmark start up to stop.
+// It uses a dedicated connection per node, so that it does not take connections from the benchmark pools.
+type serverStatsSampler struct {
+	addrs    []string
+	clients  []radix.Client
+	interval time.Duration
+	mutex    sync.Mutex
+	// per node. first and last are unset while the node did not reply to INFO
+	first       []*serverSample
+	last        []*serverSample
+	samples     []int
+	opsPerSecTs [][]float64
+	stopCh      chan struct{}
+	done        chan struct{}
+}
+
+// newServerStatsSampler connects to each node and takes its first sample, which the deltas are computed against.
+// Nodes that can't be connected to are left out of the server side summary.
+func newServerStatsSampler(network string, addrs []string, opts []radix.DialOpt, interval time.Duration) *serverStatsSampler {
+	clients := []radix.Client{}
+	connectedAddrs := []string{}
+	for _, addr := range addrs {
+		conn, err := radix.Dial(network, addr, opts...)
+		if err != nil {
+			log.Printf("Unable to connect to %s to sample INFO: %v", addr, err)
+			continue
+		}
+		connectedAddrs = append(connectedAddrs, addr)
+		clients = append(clients, conn)
+	}
+	addrs = connectedAddrs
+	s := &serverStatsSampler{
+		addrs:       addrs,
+		clients:     clients,
+		interval:    interval,
+		first:       make([]*serverSample, len(addrs)),
+		last:        make([]*serverSample, len(addrs)),
+		samples:     make([]int, len(addrs)),
+		opsPerSecTs: make([][]float64, len(addrs)),
+		stopCh:      make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	s.sample()
+	return s
+}
+
+func (s *serverStatsSampler) sample() {
+	for i, client := range s.clients {
+		info, err := getInfo(client, serverStatsSections...)
+		if err != nil {
+			log.Printf("Received an error while sampling INFO on %s: %v", s.addrs[i], err)
+			continue
+		}
+		current := &serverSample{time: time.Now(), info: info}
+		s.mutex.Lock()
+		if s.first[i] == nil {
+			s.first[i] = current
+		} else {
+			previous := s.last[i]
+			took := current.time.Sub(previous.time).Seconds()
+			s.opsPerSecTs[i] = append(s.opsPerSecTs[i], (current.ops()-previous.ops())/took)
+		}
+		s.last[i] = current
+		s.samples[i]++
+		s.mutex.Unlock()
+	}
+}
+
+func (s *serverStatsSampler) run() {
+	defer close(s.done)
+	tick := time.NewTicker(s.interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			s.sample()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// stop stops the periodic sampling, takes the last sample of each node and closes the connections.
+func (s *serverStatsSampler) stop() {
+	close(s.stopCh)
+	<-s.done
+	s.sample()
+	for _, client := range s.clients {
+		client.Close()
+	}
+}
+
+// serverCommandStats is the server side breakdown of a command issued during the run.
+type serverCommandStats struct {
+	Calls       uint64
+	UsecPerCall float64
+	// latency percentiles as reported by INFO latencystats, which are cumulative since the server start
+	// or the last CONFIG RESETSTAT, as opposed to the per run Calls and UsecPerCall
+	LatencyPercentilesUsecSinceStart map[string]float64 `json:",omitempty"`
+}
+
+// serverStatsResults is the server side summary of a node, out of the deltas between its first and last samples.
+type serverStatsResults struct {
+	Node                 string
+	Samples              int
+	DurationSecs         float64
+	OpsPerSec            float64
+	OpsPerSecTs          []float64
+	UsedCPUSysUsecPerOp  float64
+	UsedCPUUserUsecPerOp float64
+	UsedMemoryGrowth     int64
+	Commands             map[string]serverCommandStats
+}
+
+func (s *serverStatsSampler) results() []serverStatsResults {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	results := []serverStatsResults{}
+	for i, addr := range s.addrs {
+		first, last := s.first[i], s.last[i]
+		if first == nil || first == last {
+			continue
+		}
+		result := serverStatsResults{
+			Node:             addr,
+			Samples:          s.samples[i],
+			DurationSecs:     last.time.Sub(first.time).Seconds(),
+			OpsPerSecTs:      s.opsPerSecTs[i],
+			UsedMemoryGrowth: int64(last.float("used_memory") - first.float("used_memory")),
+			Commands:         map[string]serverCommandStats{},
+		}
+		ops := last.ops() - first.ops()
+		result.OpsPerSec = ops / result.DurationSecs
+		if ops > 0 {
+			result.UsedCPUSysUsecPerOp = (last.float("used_cpu_sys") - first.float("used_cpu_sys")) * 1000000 / ops
+			result.UsedCPUUserUsecPerOp = (last.float("used_cpu_user") - first.float("used_cpu_user")) * 1000000 / ops
+		}
+		for name, value := range last.info {
+			if !strings.HasPrefix(name, cmdstatPrefix) || name == cmdstatInfo {
+				continue
+			}
+			command := strings.TrimPrefix(name, cmdstatPrefix)
+			lastStats, firstStats := parseInfoFields(value), parseInfoFields(first.info[name])
+			lastCalls, _ := strconv.ParseUint(lastStats["calls"], 10, 64)
+			firstCalls, _ := strconv.ParseUint(firstStats["calls"], 10, 64)
+			if lastCalls <= firstCalls {
+				continue
+			}
+			lastUsec, _ := strconv.ParseUint(lastStats["usec"], 10, 64)
+			firstUsec, _ := strconv.ParseUint(firstStats["usec"], 10, 64)
+			stats := serverCommandStats{Calls: lastCalls - firstCalls, UsecPerCall: float64(lastUsec-firstUsec) / float64(lastCalls-firstCalls)}
+			if percentiles, found := last.info[latencyPercentilesPrefix+command]; found {
+				stats.LatencyPercentilesUsecSinceStart = map[string]float64{}
+				for percentile, usec := range parseInfoFields(percentiles) {
+					stats.LatencyPercentilesUsecSinceStart[percentile], _ = strconv.ParseFloat(usec, 64)
+				}
+			}
+			result.Commands[command] = stats
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func printServerStatsSummary(results []serverStatsResults) {
+	if len(results) == 0 {
+		return
+	}
+	fmt.Printf("#################################################\n")
+	fmt.Printf("Server side summary (INFO deltas):\n")
+	fmt.Printf("    %21s %11s %16s %17s %14s\n", "node", "ops/sec", "cpu sys usec/op", "cpu user usec/op", "memory growth")
+	for _, result := range results {
+		fmt.Printf("    %21s %11.0f %16.3f %17.3f %14d\n", result.Node, result.OpsPerSec, result.UsedCPUSysUsecPerOp, result.UsedCPUUserUsecPerOp, result.UsedMemoryGrowth)
+	}
+	fmt.Printf("Server side command summary (latency in usec). The percentiles are cumulative since the server start or the last CONFIG RESETSTAT:\n")
+	fmt.Printf("    %21s %20s %11s %13s %17s %17s %17s\n", "node", "command", "calls", "usec/call", "p50 since start", "p99 since start", "p99.9 since start")
+	for _, result := range results {
+		commands := make([]string, 0, len(result.Commands))
+		for command := range result.Commands {
+			commands = append(commands, command)
+		}
+		sort.Strings(commands)
+		for _, command := range commands {
+			stats := result.Commands[command]
+			percentiles := fmt.Sprintf("%17s %17s %17s", "-", "-", "-")
+			if stats.LatencyPercentilesUsecSinceStart != nil {
+				since := stats.LatencyPercentilesUsecSinceStart
+				percentiles = fmt.Sprintf("%17.3f %17.3f %17.3f", since["p50"], since["p99"], since["p99.9"])
+			}
+			fmt.Printf("    %21s %20s %11d %13.3f %s\n", result.Node, command, stats.Calls, stats.UsecPerCall, percentiles)
+		}
+	}
+}
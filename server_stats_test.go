@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestServerStatsSamplerResults(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	first := &serverSample{time: start, info: map[string]string{
+		"total_commands_processed":        "100",
+		"used_cpu_sys":                    "1.0",
+		"used_cpu_user":                   "2.0",
+		"used_memory":                     "1000",
+		"cmdstat_info":                    "calls=2,usec=20,usec_per_call=10.00",
+		"cmdstat_zrange":                  "calls=10,usec=100,usec_per_call=10.00",
+		"cmdstat_zadd":                    "calls=5,usec=50,usec_per_call=10.00",
+		"latency_percentiles_usec_zrange": "p50=1.000,p99=2.000,p99.9=3.000",
+		"latency_percentiles_usec_zadd":   "p50=4.000,p99=5.000,p99.9=6.000",
+	}}
+	last := &serverSample{time: start.Add(2 * time.Second), info: map[string]string{
+		// 1000 ZRANGE, 3 PING and 3 INFO calls since the first sample
+		"total_commands_processed":        "1106",
+		"used_cpu_sys":                    "1.5",
+		"used_cpu_user":                   "2.25",
+		"used_memory":                     "3000",
+		"cmdstat_info":                    "calls=5,usec=50,usec_per_call=10.00",
+		"cmdstat_zrange":                  "calls=1010,usec=2100,usec_per_call=2.08",
+		"cmdstat_zadd":                    "calls=5,usec=50,usec_per_call=10.00",
+		"cmdstat_ping":                    "calls=3,usec=3,usec_per_call=1.00",
+		"latency_percentiles_usec_zrange": "p50=1.500,p99=2.500,p99.9=3.500",
+		"latency_percentiles_usec_zadd":   "p50=4.000,p99=5.000,p99.9=6.000",
+	}}
+	s := &serverStatsSampler{
+		addrs: []string{"127.0.0.1:6379", "127.0.0.1:6380", "127.0.0.1:6381"},
+		// the second node only replied to the first sample, and the third node to none
+		first:       []*serverSample{first, first, nil},
+		last:        []*serverSample{last, first, nil},
+		samples:     []int{3, 1, 0},
+		opsPerSecTs: [][]float64{{400, 600}, nil, nil},
+	}
+	want := []serverStatsResults{{
+		Node:                 "127.0.0.1:6379",
+		Samples:              3,
+		DurationSecs:         2,
+		OpsPerSec:            1003.0 / 2,
+		OpsPerSecTs:          []float64{400, 600},
+		UsedCPUSysUsecPerOp:  0.5 * 1000000 / 1003,
+		UsedCPUUserUsecPerOp: 0.25 * 1000000 / 1003,
+		UsedMemoryGrowth:     2000,
+		Commands: map[string]serverCommandStats{
+			"zrange": {Calls: 1000, UsecPerCall: 2, LatencyPercentilesUsecSinceStart: map[string]float64{"p50": 1.5, "p99": 2.5, "p99.9": 3.5}},
+			"ping":   {Calls: 3, UsecPerCall: 1},
+		},
+	}}
+	if got := s.results(); !reflect.DeepEqual(got, want) {
+		t.Errorf("results() = %+v, want %+v", got, want)
+	}
+}
+
+func TestServerSampleOps(t *testing.T) {
+	tests := []struct {
+		name string
+		info map[string]string
+		want float64
+	}{
+		{"without INFO calls", map[string]string{"total_commands_processed": "10"}, 10},
+		{"INFO calls left out", map[string]string{"total_commands_processed": "10", "cmdstat_info": "calls=4,usec=40,usec_per_call=10.00"}, 6},
+		{"empty sample", map[string]string{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (serverSample{info: tt.info}).ops(); got != tt.want {
+				t.Errorf("ops() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,20 +1,45 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"github.com/mediocregopher/radix/v3"
+	"github.com/mediocregopher/radix/v3/resp/resp2"
 	"log"
 )
 
-func getStandaloneConn(addr string, opts []radix.DialOpt, clients uint64) *radix.Pool {
+// dialBenchConn dials a benchmark connection, switching it to RESP3 when respProtocol is 3,
+// and naming it so that it can be found on CLIENT LIST.
+func dialBenchConn(network, addr string, opts []radix.DialOpt) (radix.Conn, error) {
+	conn, err := radix.Dial(network, addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if respProtocol == 3 {
+		conn = newRESP3Conn(conn)
+		if err = conn.Do(radix.Cmd(nil, "HELLO", "3")); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("HELLO 3 failed, RESP3 requires Redis 6.0 or later: %v", err)
+		}
+	}
+	// best effort, given that the ACL user might not be allowed to name its connections
+	if err = conn.Do(radix.Cmd(nil, "CLIENT", "SETNAME", benchClientName)); err != nil && !errors.As(err, new(resp2.Error)) {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// getStandaloneConn creates a pool of clients connections to addr, which is a host:port
+// address on the tcp network or a socket path on the unix network.
+func getStandaloneConn(network string, addr string, opts []radix.DialOpt, clients uint64, poolOpts ...radix.PoolOpt) *radix.Pool {
 	var pool *radix.Pool
 	var err error
 
 	customConnFunc := func(network, addr string) (radix.Conn, error) {
-		return radix.Dial(network, addr, opts...,
-		)
+		return dialBenchConnWithBackoff(network, addr, opts)
 	}
-	network := "tcp"
-	pool, err = radix.NewPool(network, addr, int(clients), radix.PoolConnFunc(customConnFunc), radix.PoolPipelineWindow(0, 0))
+	pool, err = radix.NewPool(network, addr, int(clients), append([]radix.PoolOpt{radix.PoolConnFunc(customConnFunc), radix.PoolPipelineWindow(0, 0)}, poolOpts...)...)
 	if err != nil {
 		log.Fatalf("Error preparing for benchmark, while creating new connection. error = %v", err)
 	}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// getTLSConfig returns the TLS config shared by all connections. The client certificate is
+// optional, and when no CA certificate is given the system roots are used. When sni is empty
+// the server name is taken from the address of each dialed node.
+func getTLSConfig(certFile, keyFile, caCertFile string, skipVerify bool, sni string) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: skipVerify,
+		ServerName:         sni,
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("both the client certificate and key are required")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate %s and key %s: %v", certFile, keyFile, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caCertFile != "" {
+		caCert, err := ioutil.ReadFile(caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the CA certificate %s: %v", caCertFile, err)
+		}
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no PEM certificates found on the CA certificate %s", caCertFile)
+		}
+		config.RootCAs = caCertPool
+	}
+	return config, nil
+}